@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/codec"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/dlq"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/keyring"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/redisclient"
+)
+
+const usage = `usage: dlq <command> [arguments]
+
+commands:
+  list [-n limit]         list dead-lettered events, oldest first
+  inspect <id>            show a single record
+  requeue <id>... | -all  publish events again and remove their records
+  purge                   delete all records`
+
+var errUsage = errors.New(usage)
+
+func main() {
+	// -------- Config --------
+	redisAddr := getEnv("REDIS_ADDR", "localhost:6379")
+	channel := getEnv("CHANNEL_NAME", "broadcast.events")
+	source := getEnv("SERVER_ID", "dlq")
+	transport := getEnv("TRANSPORT", redisclient.TransportPubSub)
+	streamMaxLen := getEnv("STREAM_MAXLEN", "100000")
+	dlqKey := getEnv("DLQ_KEY", "broadcast.events.dlq")
+	codecName := getEnv("CODEC", "json")
+
+	// -------- Logger --------
+	logger := slog.New(
+		slog.NewJSONHandler(os.Stderr, nil),
+	).With("component", "dlq")
+
+	slog.SetDefault(logger)
+
+	// Redis
+	rdb, err := redisclient.New(redisAddr, 0)
+	if err != nil {
+		logger.Error("failed to connect to redis", "error", err)
+		os.Exit(1)
+	}
+
+	c, ok := codec.Named(codecName)
+	if !ok {
+		logger.Error("unknown codec", "codec", codecName)
+		os.Exit(1)
+	}
+
+	// requeued events are signed and encrypted like those of cmd/publisher
+	opts := []redisclient.PublisherOption{
+		redisclient.WithChannel(channel),
+		redisclient.WithTransport(transport),
+		redisclient.WithCodec(c),
+	}
+	maxLen, err := strconv.ParseInt(streamMaxLen, 10, 64)
+	if err != nil {
+		logger.Error("invalid STREAM_MAXLEN", "value", streamMaxLen, "error", err)
+		os.Exit(1)
+	}
+	opts = append(opts, redisclient.WithStreamMaxLen(maxLen))
+	keys, err := keyring.Load("SIGNING")
+	if err != nil {
+		logger.Error("failed to load signing keys", "error", err)
+		os.Exit(1)
+	}
+	if keys.Len() > 0 {
+		opts = append(opts, redisclient.WithSigning(keys))
+	}
+	encryptionKeys, err := keyring.Load("ENCRYPTION")
+	if err != nil {
+		logger.Error("failed to load encryption keys", "error", err)
+		os.Exit(1)
+	}
+	if encryptionKeys.Len() > 0 {
+		opts = append(opts, redisclient.WithEncryption(encryptionKeys))
+	}
+
+	store := dlq.NewRedisStore(rdb, dlqKey, dlq.WithEncryption(encryptionKeys))
+	publisher := redisclient.NewPublisher(rdb, source, opts...)
+	requeue := func(ctx context.Context, record dlq.Record) error {
+		if record.Message.Encrypted && encryptionKeys.Len() == 0 {
+			return errors.New("event was encrypted, set ENCRYPTION_KEYS or ENCRYPTION_KEY_DIR to requeue it")
+		}
+		target, err := requeueChannel(record, channel)
+		if err != nil {
+			return err
+		}
+		_, _, err = publisher.PublishTo(ctx, target, record.Message)
+		return err
+	}
+
+	if err := run(context.Background(), os.Args[1:], store, requeue, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string, store dlq.Store, requeue func(context.Context, dlq.Record) error, out io.Writer) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	switch args[0] {
+	case "list":
+		fs := flag.NewFlagSet("list", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		limit := fs.Int64("n", 0, "maximum number of records, 0 for all")
+		if err := fs.Parse(args[1:]); err != nil {
+			return errUsage
+		}
+		records, err := store.List(ctx, *limit)
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(out)
+		for _, r := range records {
+			if err := enc.Encode(printable(r)); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case "inspect":
+		if len(args) != 2 {
+			return errUsage
+		}
+		record, err := store.Get(ctx, args[1])
+		if err != nil {
+			return err
+		}
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(printable(record))
+
+	case "requeue":
+		fs := flag.NewFlagSet("requeue", flag.ContinueOnError)
+		fs.SetOutput(io.Discard)
+		all := fs.Bool("all", false, "requeue every record")
+		if err := fs.Parse(args[1:]); err != nil {
+			return errUsage
+		}
+		ids := fs.Args()
+		if *all {
+			records, err := store.List(ctx, 0)
+			if err != nil {
+				return err
+			}
+			ids = ids[:0]
+			for _, r := range records {
+				ids = append(ids, r.ID)
+			}
+		}
+		if len(ids) == 0 && !*all {
+			return errUsage
+		}
+		// on pub/sub every node that failed an event dead-letters it, so the
+		// same event may have several records; it is published only once
+		requeued := make(map[string]bool)
+		for _, id := range ids {
+			record, err := store.Get(ctx, id)
+			if err != nil {
+				return fmt.Errorf("requeue %s: %w", id, err)
+			}
+			duplicate := record.Message.ID != "" && requeued[record.Message.ID]
+			if !duplicate {
+				if err := requeue(ctx, record); err != nil {
+					return fmt.Errorf("requeue %s: %w", id, err)
+				}
+				requeued[record.Message.ID] = true
+			}
+			if err := store.Delete(ctx, id); err != nil {
+				return fmt.Errorf("requeue %s: %w", id, err)
+			}
+			if duplicate {
+				fmt.Fprintf(out, "removed %s (event %s already requeued)\n", id, record.Message.ID)
+				continue
+			}
+			fmt.Fprintf(out, "requeued %s (event %s)\n", id, record.Message.ID)
+		}
+		return nil
+
+	case "purge":
+		n, err := store.Purge(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "purged %d records\n", n)
+		return nil
+
+	default:
+		return errUsage
+	}
+}
+
+// requeueChannel returns the channel record is requeued to: the one its event
+// was received on, or fallback, CHANNEL_NAME, for records stored before the
+// channel was recorded. fallback must then name a single channel.
+func requeueChannel(record dlq.Record, fallback string) (string, error) {
+	if record.Channel != "" {
+		return record.Channel, nil
+	}
+	if strings.ContainsAny(fallback, ",*?[") {
+		return "", fmt.Errorf("record has no channel and CHANNEL_NAME %q is not a single channel", fallback)
+	}
+	return fallback, nil
+}
+
+// printable returns r as printed, with the payload of an encrypted event
+// redacted.
+func printable(r dlq.Record) any {
+	r.Message.Payload = r.Message.Redact(r.Message.Payload)
+	return struct {
+		dlq.Record
+		Encrypted bool `json:"encrypted,omitempty"`
+	}{r, r.Message.Encrypted}
+}
+
+func getEnv(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/dlq"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/events"
+)
+
+func TestGetEnv_ReturnsEnvValue(t *testing.T) {
+	key := "TEST_ENV_KEY"
+	expected := "actual_value"
+
+	os.Setenv(key, expected)
+	defer os.Unsetenv(key)
+
+	result := getEnv(key, "fallback_value")
+
+	if result != expected {
+		t.Errorf("expected %s, got %s", expected, result)
+	}
+}
+
+func TestGetEnv_ReturnsFallbackWhenNotSet(t *testing.T) {
+	key := "TEST_ENV_KEY_NOT_SET"
+	fallback := "fallback_value"
+
+	os.Unsetenv(key)
+
+	result := getEnv(key, fallback)
+
+	if result != fallback {
+		t.Errorf("expected fallback %s, got %s", fallback, result)
+	}
+}
+
+func seededStore(t *testing.T) *dlq.MemoryStore {
+	t.Helper()
+	store := dlq.NewMemoryStore()
+	for _, id := range []string{"event-1", "event-2"} {
+		if err := store.Put(context.Background(), dlq.Record{Message: events.Message{ID: id}, Error: "boom"}); err != nil {
+			t.Fatalf("unexpected put error: %v", err)
+		}
+	}
+	return store
+}
+
+func noRequeue(ctx context.Context, record dlq.Record) error {
+	return errors.New("unexpected requeue")
+}
+
+func TestRunList(t *testing.T) {
+	var out bytes.Buffer
+	if err := run(context.Background(), []string{"list", "-n", "1"}, seededStore(t), noRequeue, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 1 || !strings.Contains(lines[0], `"event-1"`) {
+		t.Fatalf("expected a single record for event-1, got %q", out.String())
+	}
+}
+
+func TestRunListRedactsEncryptedEvents(t *testing.T) {
+	store := dlq.NewMemoryStore()
+	secret := events.Message{ID: "event-1", Payload: "jane@example.com", Encrypted: true}
+	if err := store.Put(context.Background(), dlq.Record{Message: secret}); err != nil {
+		t.Fatalf("unexpected put error: %v", err)
+	}
+	var out bytes.Buffer
+	if err := run(context.Background(), []string{"list"}, store, noRequeue, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Contains(out.String(), "example.com") || !strings.Contains(out.String(), events.Redacted) ||
+		!strings.Contains(out.String(), `"encrypted":true`) {
+		t.Fatalf("expected the payload to be redacted, got %q", out.String())
+	}
+}
+
+func TestRunInspect(t *testing.T) {
+	var out bytes.Buffer
+	if err := run(context.Background(), []string{"inspect", "2"}, seededStore(t), noRequeue, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), `"event-2"`) {
+		t.Fatalf("expected record for event-2, got %q", out.String())
+	}
+
+	if err := run(context.Background(), []string{"inspect", "99"}, seededStore(t), noRequeue, &out); !errors.Is(err, dlq.ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestRunRequeue(t *testing.T) {
+	store := seededStore(t)
+	var requeued []string
+	requeue := func(ctx context.Context, record dlq.Record) error {
+		requeued = append(requeued, record.Message.ID)
+		return nil
+	}
+
+	var out bytes.Buffer
+	if err := run(context.Background(), []string{"requeue", "1"}, store, requeue, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(requeued) != 1 || requeued[0] != "event-1" {
+		t.Fatalf("expected event-1 to be requeued, got %v", requeued)
+	}
+
+	if err := run(context.Background(), []string{"requeue", "-all"}, store, requeue, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(requeued) != 2 || requeued[1] != "event-2" {
+		t.Fatalf("expected event-2 to be requeued, got %v", requeued)
+	}
+	if records, _ := store.List(context.Background(), 0); len(records) != 0 {
+		t.Fatalf("expected requeued records to be removed, got %d", len(records))
+	}
+}
+
+func TestRunRequeuePublishesEachEventOnce(t *testing.T) {
+	store := seededStore(t)
+	// a second node dead-lettered event-1 too
+	if err := store.Put(context.Background(), dlq.Record{Message: events.Message{ID: "event-1"}, Error: "boom"}); err != nil {
+		t.Fatalf("unexpected put error: %v", err)
+	}
+	var requeued []string
+	requeue := func(ctx context.Context, record dlq.Record) error {
+		requeued = append(requeued, record.Message.ID)
+		return nil
+	}
+
+	var out bytes.Buffer
+	if err := run(context.Background(), []string{"requeue", "-all"}, store, requeue, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(requeued) != 2 || requeued[0] != "event-1" || requeued[1] != "event-2" {
+		t.Fatalf("expected event-1 and event-2 to be requeued once each, got %v", requeued)
+	}
+	if !strings.Contains(out.String(), "removed 3 (event event-1 already requeued)") {
+		t.Fatalf("expected the duplicate record to be reported, got %q", out.String())
+	}
+	if records, _ := store.List(context.Background(), 0); len(records) != 0 {
+		t.Fatalf("expected every record to be removed, got %d", len(records))
+	}
+}
+
+func TestRequeueChannel(t *testing.T) {
+	if got, err := requeueChannel(dlq.Record{Channel: "orders.paid"}, "a,b"); err != nil || got != "orders.paid" {
+		t.Errorf("expected the receiving channel, got %q (%v)", got, err)
+	}
+	if got, err := requeueChannel(dlq.Record{}, "broadcast.events"); err != nil || got != "broadcast.events" {
+		t.Errorf("expected CHANNEL_NAME for a record without a channel, got %q (%v)", got, err)
+	}
+	for _, fallback := range []string{"a,b", "orders.*"} {
+		if _, err := requeueChannel(dlq.Record{}, fallback); err == nil {
+			t.Errorf("expected CHANNEL_NAME %q to be rejected", fallback)
+		}
+	}
+}
+
+func TestRunRequeueKeepsRecordOnFailure(t *testing.T) {
+	store := seededStore(t)
+	if err := run(context.Background(), []string{"requeue", "1"}, store, noRequeue, &bytes.Buffer{}); err == nil {
+		t.Fatal("expected requeue error")
+	}
+	if _, err := store.Get(context.Background(), "1"); err != nil {
+		t.Fatalf("expected record to be kept, got %v", err)
+	}
+}
+
+func TestRunPurge(t *testing.T) {
+	var out bytes.Buffer
+	if err := run(context.Background(), []string{"purge"}, seededStore(t), noRequeue, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != "purged 2 records\n" {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+}
+
+func TestRunUsage(t *testing.T) {
+	for _, args := range [][]string{nil, {"unknown"}, {"inspect"}, {"requeue"}} {
+		if err := run(context.Background(), args, seededStore(t), noRequeue, &bytes.Buffer{}); !errors.Is(err, errUsage) {
+			t.Errorf("args %v: expected usage error, got %v", args, err)
+		}
+	}
+}
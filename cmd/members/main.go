@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/membership"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/redisclient"
+)
+
+const usage = `usage: members [-json] [-channel name]
+
+lists the live subscribers with their version, channels, handler types and
+processor metrics. With -channel, only subscribers receiving broadcasts on
+that channel are listed, to compare with the receiver count of PUBLISH.`
+
+var errUsage = errors.New(usage)
+
+func main() {
+	// -------- Config --------
+	redisAddr := getEnv("REDIS_ADDR", "localhost:6379")
+	membershipKey := getEnv("MEMBERSHIP_KEY", "broadcast.members")
+
+	// -------- Logger --------
+	logger := slog.New(
+		slog.NewJSONHandler(os.Stderr, nil),
+	).With("component", "members")
+
+	slog.SetDefault(logger)
+
+	// Redis
+	rdb, err := redisclient.New(redisAddr, 0)
+	if err != nil {
+		logger.Error("failed to connect to redis", "error", err)
+		os.Exit(1)
+	}
+
+	registry := membership.New(rdb, membershipKey, 0)
+	if err := run(context.Background(), os.Args[1:], registry.Members, os.Stdout, time.Now()); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string, list func(context.Context) ([]membership.Member, error), out io.Writer, now time.Time) error {
+	fs := flag.NewFlagSet("members", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	asJSON := fs.Bool("json", false, "print one JSON object per member")
+	channel := fs.String("channel", "", "only list members receiving this channel")
+	if err := fs.Parse(args); err != nil || fs.NArg() > 0 {
+		return errUsage
+	}
+
+	members, err := list(ctx)
+	if err != nil {
+		return err
+	}
+	if *channel != "" {
+		listening := members[:0]
+		for _, m := range members {
+			if m.Listens(*channel) {
+				listening = append(listening, m)
+			}
+		}
+		members = listening
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(out)
+		for _, m := range members {
+			if err := enc.Encode(m); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tVERSION\tLAST SEEN\tUPTIME\tCHANNELS\tHANDLERS\tPROCESSED\tDROPPED\tQUEUED")
+	for _, m := range members {
+		fmt.Fprintf(w, "%s\t%s\t%s ago\t%s\t%s\t%s\t%d\t%d\t%d\n",
+			m.ID, m.Version,
+			now.Sub(m.LastSeen).Round(time.Second),
+			now.Sub(m.StartedAt).Round(time.Second),
+			strings.Join(append(m.Channels, m.Patterns...), ","),
+			strings.Join(m.HandlerTypes, ","),
+			m.Metrics["processed"], m.Metrics["dropped"], m.Metrics["queued"],
+		)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if *channel != "" {
+		fmt.Fprintf(out, "%d live subscribers on %s\n", len(members), *channel)
+	} else {
+		fmt.Fprintf(out, "%d live subscribers\n", len(members))
+	}
+	return nil
+}
+
+func getEnv(key, fallback string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fallback
+}
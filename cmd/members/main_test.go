@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/membership"
+)
+
+func staticMembers(now time.Time) func(context.Context) ([]membership.Member, error) {
+	return func(context.Context) ([]membership.Member, error) {
+		return []membership.Member{
+			{
+				ID: "server-1", Version: "1.2.0",
+				Channels: []string{"broadcast.events"}, HandlerTypes: []string{"demo.message"},
+				Metrics:   map[string]int64{"processed": 42, "queued": 3},
+				StartedAt: now.Add(-time.Hour), LastSeen: now.Add(-2 * time.Second),
+			},
+			{
+				ID: "server-2", Version: "1.1.0",
+				Patterns:  []string{"orders.*"},
+				StartedAt: now.Add(-time.Minute), LastSeen: now.Add(-time.Second),
+			},
+		}, nil
+	}
+}
+
+func TestRunTable(t *testing.T) {
+	now := time.Now()
+	var out bytes.Buffer
+	if err := run(context.Background(), nil, staticMembers(now), &out, now); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 4 || !strings.HasPrefix(lines[0], "ID") || lines[3] != "2 live subscribers" {
+		t.Fatalf("unexpected output %q", out.String())
+	}
+	for _, want := range []string{"server-1", "1.2.0", "2s ago", "1h0m0s", "broadcast.events", "demo.message", "42"} {
+		if !strings.Contains(lines[1], want) {
+			t.Errorf("expected %q in %q", want, lines[1])
+		}
+	}
+}
+
+func TestRunChannel(t *testing.T) {
+	now := time.Now()
+	var out bytes.Buffer
+	if err := run(context.Background(), []string{"-json", "-channel", "orders.created"}, staticMembers(now), &out, now); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 1 || !strings.Contains(lines[0], `"id":"server-2"`) {
+		t.Fatalf("expected only server-2 as JSON, got %q", out.String())
+	}
+
+	if err := run(context.Background(), []string{"extra"}, staticMembers(now), &out, now); !errors.Is(err, errUsage) {
+		t.Fatalf("expected errUsage, got %v", err)
+	}
+}
@@ -2,15 +2,17 @@ package main
 
 import (
 	"context"
-	"encoding/json"
 	"log/slog"
 	"os"
+	"strconv"
 	"time"
 
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/codec"
 	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/events"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/keyring"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/membership"
 	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/redisclient"
-
-	"github.com/google/uuid"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/tracing"
 )
 
 func main() {
@@ -18,6 +20,15 @@ func main() {
 	redisAddr := getEnv("REDIS_ADDR", "localhost:6379")
 	channel := getEnv("CHANNEL_NAME", "broadcast.events")
 	source := getEnv("SERVER_ID", "publisher")
+	transport := getEnv("TRANSPORT", redisclient.TransportPubSub)
+	traceExporter := getEnv("TRACE_EXPORTER", "none")
+	codecName := getEnv("CODEC", "json")
+	compression := getEnv("COMPRESSION", "")
+	compressionThreshold := getEnv("COMPRESSION_THRESHOLD", "1024")
+	streamMaxLen := getEnv("STREAM_MAXLEN", "100000")
+	collectAcks := os.Getenv("COLLECT_ACKS") == "true"
+	ackTimeout := getEnv("ACK_TIMEOUT", "5s")
+	membershipKey := getEnv("MEMBERSHIP_KEY", "broadcast.members")
 
 	// -------- Logger --------
 	logger := slog.New(
@@ -26,6 +37,17 @@ func main() {
 
 	slog.SetDefault(logger)
 
+	// -------- Tracing --------
+	if traceExporter == "stdout" {
+		exporter, err := tracing.NewStdoutExporter(os.Stderr)
+		if err != nil {
+			logger.Error("failed to create trace exporter", "error", err)
+			os.Exit(1)
+		}
+		provider := tracing.Setup(source, exporter)
+		defer func() { _ = provider.Shutdown(context.Background()) }()
+	}
+
 	// Redis
 	rdb, err := redisclient.New(redisAddr, 0)
 	if err != nil {
@@ -33,34 +55,118 @@ func main() {
 		os.Exit(1)
 	}
 
+	c, ok := codec.Named(codecName)
+	if !ok {
+		logger.Error("unknown codec", "codec", codecName)
+		os.Exit(1)
+	}
+
+	opts := []redisclient.PublisherOption{
+		redisclient.WithChannel(channel),
+		redisclient.WithTransport(transport),
+		redisclient.WithCodec(c),
+	}
+	maxLen, err := strconv.ParseInt(streamMaxLen, 10, 64)
+	if err != nil {
+		logger.Error("invalid STREAM_MAXLEN", "value", streamMaxLen, "error", err)
+		os.Exit(1)
+	}
+	opts = append(opts, redisclient.WithStreamMaxLen(maxLen))
+	if compression != "" {
+		threshold, err := strconv.Atoi(compressionThreshold)
+		if err != nil {
+			logger.Error("invalid COMPRESSION_THRESHOLD", "value", compressionThreshold, "error", err)
+			os.Exit(1)
+		}
+		opts = append(opts, redisclient.WithCompression(compression, threshold))
+	}
+
+	keys, err := keyring.Load("SIGNING")
+	if err != nil {
+		logger.Error("failed to load signing keys", "error", err)
+		os.Exit(1)
+	}
+	if keys.Len() > 0 {
+		opts = append(opts, redisclient.WithSigning(keys))
+	}
+	encryptionKeys, err := keyring.Load("ENCRYPTION")
+	if err != nil {
+		logger.Error("failed to load encryption keys", "error", err)
+		os.Exit(1)
+	}
+	if encryptionKeys.Len() > 0 {
+		opts = append(opts, redisclient.WithEncryption(encryptionKeys))
+	}
+
+	timeout, err := time.ParseDuration(ackTimeout)
+	if err != nil {
+		logger.Error("invalid ACK_TIMEOUT", "value", ackTimeout, "error", err)
+		os.Exit(1)
+	}
+	opts = append(opts, redisclient.WithAckTimeout(timeout))
+
+	publisher := redisclient.NewPublisher(rdb, source, opts...)
+	members := membership.New(rdb, membershipKey, 0)
+
 	ctx := context.Background()
 
 	for i := 1; i <= 5; i++ {
 		event := events.Message{
-			ID:        uuid.NewString(),
-			Type:      "demo.message",
-			Source:    source,
-			Timestamp: time.Now(),
+			Type: "demo.message",
 			Payload: map[string]any{
 				"counter": i,
 				"text":    "hello from publisher",
 			},
 		}
-		data, err := json.Marshal(event)
-		if err != nil {
-			logger.Error("failed to marshall event", "error", err)
+		if collectAcks {
+			publishAndCollect(ctx, logger, publisher, members, channel, transport, event)
+			time.Sleep(2 * time.Second)
 			continue
 		}
-		if err := rdb.Publish(ctx, channel, data).Err(); err != nil {
-			logger.Error("failed to publis message", "error", err)
+		id, receivers, err := publisher.Publish(ctx, event)
+		if err != nil {
+			logger.Error("failed to publish message", "error", err)
 			continue
 		}
-		logger.Info("message published", "event_id", event.ID, "type", event.Type)
+		logger.Info("message published", "event_id", id, "type", event.Type, "receivers", receivers)
 		time.Sleep(2 * time.Second)
 	}
 
 }
 
+// publishAndCollect publishes event and waits for an ack from every live
+// subscriber listening on channel according to the membership registry. On
+// the streams transport a single consumer of the group handles the event, so
+// it waits for one ack.
+func publishAndCollect(ctx context.Context, logger *slog.Logger, publisher *redisclient.Publisher, members *membership.Membership, channel, transport string, event events.Message) {
+	var expected []string
+	if transport != redisclient.TransportStreams {
+		live, err := members.Members(ctx)
+		if err != nil {
+			logger.Error("failed to list members", "error", err)
+			return
+		}
+		for _, m := range live {
+			if m.Listens(channel) {
+				expected = append(expected, m.ID)
+			}
+		}
+	}
+
+	report, err := publisher.PublishAndCollect(ctx, event, expected)
+	failed := make([]string, 0)
+	for _, ack := range report.Failed() {
+		failed = append(failed, ack.Node+": "+ack.Error)
+	}
+	attrs := []any{"event_id", report.EventID, "type", event.Type, "receivers", report.Receivers,
+		"expected", len(expected), "acked", len(report.Acks), "failed", failed, "missing", report.Missing}
+	if err != nil {
+		logger.Error("acks incomplete", append(attrs, "error", err)...)
+		return
+	}
+	logger.Info("message acknowledged", attrs...)
+}
+
 func getEnv(key, fallback string) string {
 	if v := os.Getenv(key); v != "" {
 		return v
@@ -2,23 +2,63 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
+	"strings"
+	"sync"
 	"syscall"
+	"time"
 
 	"log/slog"
 
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/dedup"
 	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/dispatcher"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/dlq"
 	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/handlers"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/keyring"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/leader"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/membership"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/metrics"
 	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/processor"
 	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/redisclient"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/tracing"
 )
 
+// version is reported in the membership registry. Set it at build time with
+// -ldflags "-X main.version=...".
+var version = "dev"
+
 func main() {
 	// -------- Config --------
 	redisAddr := getEnv("REDIS_ADDR", "localhost:6379")
 	channel := getEnv("CHANNEL_NAME", "broadcast.events")
 	serverID := getEnv("SERVER_ID", "unknown-server")
+	transport := getEnv("TRANSPORT", redisclient.TransportPubSub)
+	group := getEnv("CONSUMER_GROUP", "broadcast")
+	dlqKey := getEnv("DLQ_KEY", "broadcast.events.dlq")
+	partitionKey := os.Getenv("PARTITION_KEY")
+	// blocking only delays reading stream entries, while Redis disconnects a
+	// pub/sub reader that falls too far behind
+	overflowDefault := processor.DropNewest.String()
+	if transport == redisclient.TransportStreams {
+		overflowDefault = processor.Block.String()
+	}
+	overflow := getEnv("OVERFLOW_POLICY", overflowDefault)
+	// every pub/sub node receives its own copy of each event, so each needs
+	// its own spill list or nodes would handle each other's spilled events
+	spillKey := getEnv("SPILL_KEY", "broadcast.events.spill:"+serverID)
+	metricsAddr := getEnv("METRICS_ADDR", ":9090")
+	traceExporter := getEnv("TRACE_EXPORTER", "none")
+	signaturePolicy := getEnv("SIGNATURE_POLICY", "optional")
+	signedTypes := os.Getenv("SIGNED_EVENT_TYPES")
+	dedupStore := getEnv("DEDUP", "none")
+	dedupTypes := os.Getenv("DEDUP_TYPES")
+	dedupTTL := getEnv("DEDUP_TTL", "10m")
+	leaderKey := getEnv("LEADER_KEY", "broadcast.leader")
+	dlqRetention := getEnv("DLQ_RETENTION", "0")
+	membershipKey := getEnv("MEMBERSHIP_KEY", "broadcast.members")
 
 	// -------- Logger --------
 	logger := slog.New(
@@ -27,6 +67,18 @@ func main() {
 
 	slog.SetDefault(logger)
 
+	// -------- Tracing --------
+	stopTracing := func() {}
+	if traceExporter == "stdout" {
+		exporter, err := tracing.NewStdoutExporter(os.Stderr)
+		if err != nil {
+			logger.Error("failed to create trace exporter", "error", err)
+			os.Exit(1)
+		}
+		provider := tracing.Setup(serverID, exporter)
+		stopTracing = func() { _ = provider.Shutdown(context.Background()) }
+	}
+
 	// -------- Redis --------
 	rdb, err := redisclient.New(redisAddr, 0)
 	if err != nil {
@@ -36,12 +88,194 @@ func main() {
 
 	ctx := context.Background()
 
+	// -------- Keys --------
+	keys, err := keyring.Load("SIGNING")
+	if err != nil {
+		logger.Error("failed to load signing keys", "error", err)
+		os.Exit(1)
+	}
+	// encrypted events are encrypted again before they are spilled or
+	// dead-lettered
+	encryptionKeys, err := keyring.Load("ENCRYPTION")
+	if err != nil {
+		logger.Error("failed to load encryption keys", "error", err)
+		os.Exit(1)
+	}
+
+	m := metrics.New()
+
 	d := dispatcher.New(logger)
+	m.WatchDispatcher(d)
+	const handlerTimeout = 30 * time.Second
+	d.Use(dispatcher.Recovery(logger), dispatcher.Latency(m.ObserveHandler), dispatcher.Timeout(handlerTimeout))
 	d.Register("demo.message", handlers.NewDemoMessageHandler(logger))
 
-	p := processor.New(d, logger, 4, 100)
+	deadLetters := dlq.NewRedisStore(rdb, dlqKey, dlq.WithEncryption(encryptionKeys))
+	retry := processor.ExponentialRetry{
+		Initial:    100 * time.Millisecond,
+		MaxDelay:   5 * time.Second,
+		Multiplier: 2,
+		Jitter:     0.2,
+		Retries:    3,
+	}
+	maxHandling := maxHandlingTime(retry, handlerTimeout)
+	opts := []processor.Option{
+		processor.WithObserver(m),
+		processor.WithDeadLetter(deadLetters),
+		processor.WithRetryPolicy(retry),
+		processor.WithDedupClaimTTL(maxHandling),
+	}
+	if key := partitionKeyFor(partitionKey); key != nil {
+		opts = append(opts, processor.WithPartitionKey(key))
+	}
+	policy, ok := overflowPolicyFor(overflow)
+	if !ok {
+		logger.Error("unknown overflow policy", "overflow_policy", overflow)
+		os.Exit(1)
+	}
+	if policy == processor.Spill && partitionKey != "" {
+		// spilled events come back after later ones with the same key
+		logger.Error("OVERFLOW_POLICY=spill does not keep the order PARTITION_KEY asks for", "partition_key", partitionKey)
+		os.Exit(1)
+	}
+	opts = append(opts, processor.WithOverflowPolicy(policy))
+	if policy == processor.Spill {
+		spiller := redisclient.NewListSpiller(rdb, spillKey, redisclient.WithSpillEncryption(encryptionKeys))
+		// the spill list is this node's own, so nothing else is popping it yet
+		n, err := spiller.Recover(ctx)
+		if err != nil {
+			logger.Error("failed to recover spilled events", "spill_key", spillKey, "error", err)
+			os.Exit(1)
+		}
+		if n > 0 {
+			logger.Warn("recovered spilled events left by a previous run", "spill_key", spillKey, "recovered", n)
+		}
+		opts = append(opts, processor.WithSpiller(spiller))
+	}
+	ttl, err := time.ParseDuration(dedupTTL)
+	if err != nil {
+		logger.Error("invalid DEDUP_TTL", "value", dedupTTL, "error", err)
+		os.Exit(1)
+	}
+	dedupOpts, err := dedupOptions(dedupStore, dedupTypes, map[string]dedup.Store{
+		"memory": dedup.NewMemory(100_000, ttl),
+		"redis":  dedup.NewRedis(rdb, "broadcast.dedup:", ttl),
+	})
+	if err != nil {
+		logger.Error("invalid deduplication config", "error", err)
+		os.Exit(1)
+	}
+	opts = append(opts, dedupOpts...)
+	p := processor.New(d, logger, 4, 100, opts...)
+	m.WatchProcessor(p)
+
+	// -------- Signatures and encryption --------
+	verifier, ok := verifierFor(keys, signaturePolicy, signedTypes)
+	if !ok {
+		logger.Error("unknown signature policy", "signature_policy", signaturePolicy)
+		os.Exit(1)
+	}
+	streamOpts := []redisclient.StreamOption{
+		redisclient.WithStreamCompressionObserver(m.ObserveCompression),
+		redisclient.WithStreamAcks(serverID),
+		redisclient.WithClaimMinIdle(maxHandling),
+	}
+	subOpts := []redisclient.SubscriberOption{
+		redisclient.WithCompressionObserver(m.ObserveCompression),
+		redisclient.WithAcks(serverID),
+	}
+	if verifier != nil {
+		m.WatchVerifier(verifier)
+		streamOpts = append(streamOpts, redisclient.WithStreamVerifier(verifier))
+		subOpts = append(subOpts, redisclient.WithVerifier(verifier))
+	}
+	if encryptionKeys.Len() > 0 {
+		streamOpts = append(streamOpts, redisclient.WithStreamDecryption(encryptionKeys))
+		subOpts = append(subOpts, redisclient.WithDecryption(encryptionKeys))
+	}
+	go reloadKeysOnHangup(logger, map[string]*keyring.Keyring{
+		"SIGNING_KEY_DIR":    keys,
+		"ENCRYPTION_KEY_DIR": encryptionKeys,
+	})
+
+	var sub interface {
+		Start(ctx context.Context) error
+	}
+	channels, patterns := parseChannels(channel)
+	listening := func() ([]string, []string) { return channels, patterns }
+	if transport == redisclient.TransportStreams {
+		if len(channels) != 1 || len(patterns) != 0 {
+			logger.Error("streams transport needs exactly one stream name", "channel_name", channel)
+			os.Exit(1)
+		}
+		sub = redisclient.NewStreamSubscriber(rdb, channels[0], group, serverID, p, logger, streamOpts...)
+	} else {
+		subOpts = append(subOpts,
+			redisclient.WithChannels(channels...),
+			redisclient.WithPatterns(patterns...),
+		)
+		s := redisclient.NewSubscriber(rdb, "", p, logger, subOpts...)
+		m.WatchSubscriber(s)
+		listening = func() ([]string, []string) { return s.Channels(), s.Patterns() }
+		sub = s
+	}
+
+	// -------- Metrics --------
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m.Handler())
+	go func() {
+		logger.Info("serving metrics", "addr", metricsAddr)
+		if err := http.ListenAndServe(metricsAddr, mux); err != nil {
+			logger.Error("metrics server stopped", "error", err)
+		}
+	}()
+
+	// -------- Leader election --------
+	retention, err := time.ParseDuration(dlqRetention)
+	if err != nil {
+		logger.Error("invalid DLQ_RETENTION", "value", dlqRetention, "error", err)
+		os.Exit(1)
+	}
+	elector := leader.New(rdb, leaderKey, serverID, logger,
+		leader.OnElected(func(ctx context.Context, token int64) {
+			// only this lease may delete records, not a successor's
+			store := deadLetters.Fenced(leaderKey, leader.LeaseValue(serverID, token))
+			maintain(ctx, token, logger, store, retention, time.Minute)
+		}),
+	)
+	background, stopBackground := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		_ = elector.Run(background)
+	}()
 
-	sub := redisclient.NewSubscriber(rdb, channel, p, logger)
+	// -------- Membership --------
+	startedAt := time.Now()
+	members := membership.New(rdb, membershipKey, 15*time.Second)
+	go func() {
+		defer wg.Done()
+		members.Run(background, 5*time.Second, logger, func() membership.Member {
+			channels, patterns := listening()
+			return membership.Member{
+				ID:           serverID,
+				Version:      version,
+				Channels:     channels,
+				Patterns:     patterns,
+				HandlerTypes: d.EventTypes(),
+				Metrics:      p.GetMetrics(),
+				StartedAt:    startedAt,
+			}
+		})
+	}()
+	stop := func() {
+		// hand leadership over and leave the registry before draining
+		stopBackground()
+		wg.Wait()
+		p.Stop()
+		stopTracing()
+	}
 
 	// Setup signal handling for graceful shutdown
 	sigChan := make(chan os.Signal, 1)
@@ -50,15 +284,148 @@ func main() {
 	go func() {
 		<-sigChan
 		logger.Info("shutdown signal received")
-		p.Stop()
+		stop()
 		os.Exit(0)
 	}()
 
 	if err := sub.Start(ctx); err != nil {
 		logger.Error("subscriber stopped", "error", err)
-		p.Stop()
+		stop()
+	}
+
+}
+
+// dedupOptions maps DEDUP, the store used for every event type, and
+// DEDUP_TYPES, comma-separated type=store overrides, to processor options.
+// Stores are "none", "memory" (once per node) or "redis" (once per fleet).
+func dedupOptions(value, types string, stores map[string]dedup.Store) ([]processor.Option, error) {
+	lookup := func(name string) (dedup.Store, error) {
+		if name == "none" {
+			return nil, nil
+		}
+		store, ok := stores[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown dedup store %q", name)
+		}
+		return store, nil
+	}
+	store, err := lookup(value)
+	if err != nil {
+		return nil, err
 	}
+	opts := []processor.Option{processor.WithDeduplication(store)}
+	for _, entry := range strings.Split(types, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		eventType, name, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid DEDUP_TYPES entry %q, want type=store", entry)
+		}
+		store, err := lookup(name)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, processor.WithEventDeduplication(eventType, store))
+	}
+	return opts, nil
+}
+
+// maxHandlingTime returns how long an event may take to handle in the worst
+// case, every attempt running into the handler timeout, plus a minute for the
+// outcome to be settled. Neither a dedup claim nor a pending stream entry may
+// be taken over sooner, or another node would handle the event too.
+func maxHandlingTime(retry processor.ExponentialRetry, handlerTimeout time.Duration) time.Duration {
+	retry.Jitter = 0 // jitter only shortens delays
+	ttl := time.Duration(retry.MaxRetries()+1)*handlerTimeout + time.Minute
+	for attempt := 1; attempt <= retry.MaxRetries(); attempt++ {
+		ttl += retry.Delay(attempt)
+	}
+	return ttl
+}
 
+// reloadKeysOnHangup reloads every keyring loaded from a key directory when
+// the process receives SIGHUP, so keys can be rotated without a restart.
+func reloadKeysOnHangup(logger *slog.Logger, keyrings map[string]*keyring.Keyring) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	for range hup {
+		for env, keys := range keyrings {
+			dir := os.Getenv(env)
+			if dir == "" {
+				continue
+			}
+			if err := keys.LoadDir(dir); err != nil {
+				logger.Error("failed to reload keys, keeping the old ones", "dir", dir, "error", err)
+				continue
+			}
+			logger.Info("keys reloaded", "dir", dir, "key_ids", keys.IDs())
+		}
+	}
+}
+
+// parseChannels splits a comma-separated CHANNEL_NAME into plain channels and
+// glob patterns, which are recognised by the presence of *, ? or [.
+func parseChannels(value string) (channels, patterns []string) {
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		switch {
+		case name == "":
+		case strings.ContainsAny(name, "*?["):
+			patterns = append(patterns, name)
+		default:
+			channels = append(channels, name)
+		}
+	}
+	return channels, patterns
+}
+
+// partitionKeyFor maps PARTITION_KEY to a key extractor: "key" uses the
+// message key and anything else names a payload field. Empty disables
+// partitioning.
+func partitionKeyFor(value string) processor.PartitionKey {
+	switch value {
+	case "":
+		return nil
+	case "key":
+		return processor.MessageKey
+	default:
+		return processor.PayloadField(value)
+	}
+}
+
+// overflowPolicyFor parses OVERFLOW_POLICY.
+func overflowPolicyFor(value string) (processor.OverflowPolicy, bool) {
+	for _, policy := range []processor.OverflowPolicy{processor.DropNewest, processor.DropOldest, processor.Block, processor.Spill} {
+		if policy.String() == value {
+			return policy, true
+		}
+	}
+	return 0, false
+}
+
+// verifierFor builds the signature verifier from SIGNATURE_POLICY, "optional"
+// or "required", and SIGNED_EVENT_TYPES, a comma-separated list of event types
+// that must be signed under the optional policy. It returns nil when nothing
+// needs verifying.
+func verifierFor(keys *keyring.Keyring, policy, signedTypes string) (*redisclient.Verifier, bool) {
+	var opts []redisclient.VerifierOption
+	for _, eventType := range strings.Split(signedTypes, ",") {
+		if eventType = strings.TrimSpace(eventType); eventType != "" {
+			opts = append(opts, redisclient.WithEventSignaturePolicy(eventType, redisclient.SignatureRequired))
+		}
+	}
+	switch policy {
+	case "optional":
+		if keys.Len() == 0 && len(opts) == 0 {
+			return nil, true
+		}
+		return redisclient.NewVerifier(keys, redisclient.SignatureOptional, opts...), true
+	case "required":
+		return redisclient.NewVerifier(keys, redisclient.SignatureRequired, opts...), true
+	default:
+		return nil, false
+	}
 }
 
 func getEnv(key, fallback string) string {
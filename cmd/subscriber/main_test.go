@@ -1,8 +1,19 @@
 package main
 
 import (
+	"context"
+	"log/slog"
 	"os"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/dedup"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/dlq"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/keyring"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/processor"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
 )
 
 func TestGetEnv_ReturnsEnvValue(t *testing.T) {
@@ -31,3 +42,106 @@ func TestGetEnv_ReturnsFallbackWhenNotSet(t *testing.T) {
 		t.Errorf("expected fallback %s, got %s", fallback, result)
 	}
 }
+
+func TestParseChannels(t *testing.T) {
+	channels, patterns := parseChannels("broadcast.events, orders.*,tenant:*:events,,audit")
+
+	if len(channels) != 2 || channels[0] != "broadcast.events" || channels[1] != "audit" {
+		t.Errorf("expected channels [broadcast.events audit], got %v", channels)
+	}
+	if len(patterns) != 2 || patterns[0] != "orders.*" || patterns[1] != "tenant:*:events" {
+		t.Errorf("expected patterns [orders.* tenant:*:events], got %v", patterns)
+	}
+}
+
+func TestOverflowPolicyFor(t *testing.T) {
+	for _, name := range []string{"drop-newest", "drop-oldest", "block", "spill"} {
+		policy, ok := overflowPolicyFor(name)
+		if !ok || policy.String() != name {
+			t.Errorf("expected %s to parse, got %v (%v)", name, policy, ok)
+		}
+	}
+	if _, ok := overflowPolicyFor("drop-all"); ok {
+		t.Error("expected unknown policy to be rejected")
+	}
+}
+
+func TestVerifierFor(t *testing.T) {
+	keys := keyring.New()
+	if v, ok := verifierFor(keys, "optional", ""); !ok || v != nil {
+		t.Errorf("expected no verifier without keys, got %v (%v)", v, ok)
+	}
+	if v, ok := verifierFor(keys, "optional", "order.created"); !ok || v == nil {
+		t.Errorf("expected a verifier for signed event types, got %v (%v)", v, ok)
+	}
+	if v, ok := verifierFor(keys, "required", ""); !ok || v == nil {
+		t.Errorf("expected a verifier for the required policy, got %v (%v)", v, ok)
+	}
+	if _, ok := verifierFor(keys, "sometimes", ""); ok {
+		t.Error("expected unknown policy to be rejected")
+	}
+}
+
+func TestDedupOptions(t *testing.T) {
+	stores := map[string]dedup.Store{"memory": dedup.NewMemory(10, time.Minute)}
+	if opts, err := dedupOptions("none", "order.created=memory, metrics.sampled=none", stores); err != nil || len(opts) != 3 {
+		t.Errorf("expected 3 options, got %d (%v)", len(opts), err)
+	}
+	for _, tc := range [][2]string{{"redis", ""}, {"memory", "order.created"}, {"memory", "order.created=disk"}} {
+		if _, err := dedupOptions(tc[0], tc[1], stores); err == nil {
+			t.Errorf("expected DEDUP=%s DEDUP_TYPES=%s to be rejected", tc[0], tc[1])
+		}
+	}
+}
+
+func TestMaxHandlingTime(t *testing.T) {
+	retry := processor.ExponentialRetry{Initial: time.Second, Multiplier: 2, Jitter: 0.5, Retries: 3}
+	// 4 attempts of 30s, 1s+2s+4s between them and a minute to settle
+	if got, want := maxHandlingTime(retry, 30*time.Second), 187*time.Second; got != want {
+		t.Errorf("expected a maximum handling time of %s, got %s", want, got)
+	}
+}
+
+func TestSweepDLQ(t *testing.T) {
+	ctx := context.Background()
+	logger := slog.New(slog.DiscardHandler)
+	store := dlq.NewMemoryStore()
+	now := time.Now()
+	for _, age := range []time.Duration{48 * time.Hour, time.Hour, 25 * time.Hour} {
+		if err := store.Put(ctx, dlq.Record{FailedAt: now.Add(-age)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if removed, err := sweepDLQ(ctx, logger, store, 0, now); err != nil || removed != 0 {
+		t.Errorf("expected zero retention to keep every record, removed %d (%v)", removed, err)
+	}
+	if removed, err := sweepDLQ(ctx, logger, store, 24*time.Hour, now); err != nil || removed != 2 {
+		t.Errorf("expected 2 old records removed, removed %d (%v)", removed, err)
+	}
+	if records, _ := store.List(ctx, 0); len(records) != 1 {
+		t.Errorf("expected 1 record left, got %d", len(records))
+	}
+}
+
+func TestSweepDLQSkipsUndecodableRecords(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	store := dlq.NewRedisStore(client, "dlq")
+	for _, values := range []map[string]any{{"record": "not json"}, {"record": `{"id":"","error":"boom"}`}} {
+		if err := client.XAdd(ctx, &redis.XAddArgs{Stream: "dlq", Values: values}).Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var logs strings.Builder
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+	removed, err := sweepDLQ(ctx, logger, store, time.Hour, time.Now().Add(2*time.Hour))
+	if err != nil || removed != 1 {
+		t.Fatalf("expected the decodable record to be removed, removed %d (%v)", removed, err)
+	}
+	if n, _ := client.XLen(ctx, "dlq").Result(); n != 1 || !strings.Contains(logs.String(), "skipping undecodable dead-letter record") {
+		t.Fatalf("expected the undecodable record to be logged and kept, %d left, logs %s", n, logs.String())
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/dlq"
+)
+
+// maintain runs the cluster-wide maintenance tasks every interval while this
+// instance is the leader, until ctx is cancelled.
+func maintain(ctx context.Context, token int64, logger *slog.Logger, store dlq.Store, retention, interval time.Duration) {
+	logger = logger.With("leader_token", token)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		removed, err := sweepDLQ(ctx, logger, store, retention, time.Now())
+		if err != nil && ctx.Err() == nil {
+			logger.Error("failed to sweep dead-letter queue", "removed", removed, "error", err)
+		} else if removed > 0 {
+			logger.Info("dead-letter queue swept", "removed", removed)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sweepDLQ deletes dead-letter records that failed more than retention before
+// now and returns how many were removed. Only records older than that are
+// read, and one that cannot be decoded, say because its key was rotated out,
+// is logged and left for cmd/dlq. A retention of zero keeps every record.
+func sweepDLQ(ctx context.Context, logger *slog.Logger, store dlq.Store, retention time.Duration, now time.Time) (removed int, err error) {
+	if retention <= 0 {
+		return 0, nil
+	}
+	err = store.ScanBefore(ctx, now.Add(-retention), func(id string, _ dlq.Record, err error) error {
+		if err != nil {
+			logger.Warn("skipping undecodable dead-letter record", "record_id", id, "error", err)
+			return nil
+		}
+		if err := store.Delete(ctx, id); err != nil && !errors.Is(err, dlq.ErrNotFound) {
+			return err
+		}
+		removed++
+		return nil
+	})
+	return removed, err
+}
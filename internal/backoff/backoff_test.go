@@ -0,0 +1,42 @@
+package backoff
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExponentialWithoutJitter(t *testing.T) {
+	b := Exponential{Initial: 100 * time.Millisecond, Max: time.Second, Multiplier: 2}
+
+	expected := []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+		time.Second,
+	}
+	for i, want := range expected {
+		if got := b.Delay(i + 1); got != want {
+			t.Fatalf("attempt %d: expected %s, got %s", i+1, want, got)
+		}
+	}
+}
+
+func TestExponentialJitterStaysInRange(t *testing.T) {
+	b := Exponential{Initial: 100 * time.Millisecond, Max: time.Second, Multiplier: 2, Jitter: 0.5}
+
+	for i := 0; i < 100; i++ {
+		got := b.Delay(3)
+		if got < 200*time.Millisecond || got > 400*time.Millisecond {
+			t.Fatalf("expected delay within [200ms, 400ms], got %s", got)
+		}
+	}
+}
+
+func TestExponentialClampsAttempt(t *testing.T) {
+	b := Exponential{Initial: 100 * time.Millisecond, Multiplier: 2}
+	if got := b.Delay(0); got != 100*time.Millisecond {
+		t.Fatalf("expected attempt 0 to be treated as 1, got %s", got)
+	}
+}
@@ -0,0 +1,82 @@
+package codec
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/events"
+)
+
+type benchItem struct {
+	SKU      string  `json:"sku"`
+	Quantity int     `json:"quantity"`
+	Price    float64 `json:"price"`
+}
+
+type benchOrder struct {
+	OrderID  string      `json:"order_id"`
+	Customer string      `json:"customer"`
+	Total    float64     `json:"total"`
+	Items    []benchItem `json:"items"`
+}
+
+func benchEvent() events.Message {
+	order := benchOrder{OrderID: "o-1", Customer: "c-42", Total: 99.5}
+	for i := range 20 {
+		order.Items = append(order.Items, benchItem{SKU: fmt.Sprintf("sku-%d", i), Quantity: i, Price: 1.25})
+	}
+	return events.Message{
+		ID:        "550e8400-e29b-41d4-a716-446655440000",
+		Type:      "order.created",
+		Source:    "bench",
+		Timestamp: time.Now(),
+		Headers:   map[string]string{events.HeaderCorrelationID: "7d6f0a3c-6c1e-4c39-9a3e-0b7cf1c7a7e2"},
+		Payload:   order,
+	}
+}
+
+// BenchmarkCodecs compares encoding, decoding the envelope, and decoding the
+// envelope plus the typed payload for every codec. bytes/msg is the encoded
+// size.
+func BenchmarkCodecs(b *testing.B) {
+	event := benchEvent()
+	for _, c := range allCodecs {
+		data, err := Encode(c, event)
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		b.Run(c.ContentType()+"/encode", func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				if _, err := Encode(c, event); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(len(data)), "bytes/msg")
+		})
+		b.Run(c.ContentType()+"/decode", func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				var m events.Message
+				if err := Decode(data, &m); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(c.ContentType()+"/decode-typed", func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				var m events.Message
+				if err := Decode(data, &m); err != nil {
+					b.Fatal(err)
+				}
+				var order benchOrder
+				if err := m.DecodePayload(&order); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package codec
+
+import (
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/events"
+
+	"github.com/fxamacker/cbor/v2"
+)
+
+// CBOR encodes events as CBOR (RFC 8949). Payload structs without cbor tags
+// are encoded using their json tags.
+type CBOR struct{}
+
+type cborMessage struct {
+	ID        string            `cbor:"id"`
+	Type      string            `cbor:"type"`
+	Source    string            `cbor:"source"`
+	Timestamp time.Time         `cbor:"timestamp"`
+	Key       string            `cbor:"key,omitempty"`
+	Headers   map[string]string `cbor:"headers,omitempty"`
+	Payload   cbor.RawMessage   `cbor:"payload"`
+}
+
+var (
+	cborEnc cbor.EncMode
+	cborDec cbor.DecMode
+)
+
+func init() {
+	var err error
+	// keep nanosecond timestamps, as JSON does
+	cborEnc, err = cbor.EncOptions{Time: cbor.TimeRFC3339Nano, TimeTag: cbor.EncTagRequired}.EncMode()
+	if err != nil {
+		panic(err)
+	}
+	cborDec, err = cbor.DecOptions{DefaultMapType: reflect.TypeOf(map[string]any(nil))}.DecMode()
+	if err != nil {
+		panic(err)
+	}
+}
+
+func (CBOR) ContentType() string {
+	return "application/cbor"
+}
+
+func (CBOR) Marshal(event events.Message) ([]byte, error) {
+	payload, err := cborPayload(event.Payload)
+	if err != nil {
+		return nil, err
+	}
+	return cborEnc.Marshal(cborMessage{
+		ID:        event.ID,
+		Type:      event.Type,
+		Source:    event.Source,
+		Timestamp: event.Timestamp,
+		Key:       event.Key,
+		Headers:   event.Headers,
+		Payload:   payload,
+	})
+}
+
+func (CBOR) Unmarshal(data []byte, event *events.Message) error {
+	var m cborMessage
+	if err := cborDec.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	*event = events.Message{
+		ID:        m.ID,
+		Type:      m.Type,
+		Source:    m.Source,
+		Timestamp: m.Timestamp,
+		Key:       m.Key,
+		Headers:   m.Headers,
+	}
+	if len(m.Payload) > 0 && m.Payload[0] != cborNull {
+		event.Payload = CBORPayload(m.Payload)
+	}
+	return nil
+}
+
+const cborNull = 0xf6
+
+// CBORPayload is a payload still encoded as CBOR.
+type CBORPayload []byte
+
+func (p CBORPayload) DecodePayload(v any) error {
+	return cborDec.Unmarshal(p, v)
+}
+
+// MarshalJSON converts the payload, so events decoded from CBOR can be stored
+// or re-published as JSON.
+func (p CBORPayload) MarshalJSON() ([]byte, error) {
+	var v any
+	if err := p.DecodePayload(&v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+func cborPayload(payload any) (cbor.RawMessage, error) {
+	if raw, ok := payload.(CBORPayload); ok {
+		return cbor.RawMessage(raw), nil
+	}
+	v, err := plainPayload(payload)
+	if err != nil {
+		return nil, err
+	}
+	return cborEnc.Marshal(v)
+}
@@ -0,0 +1,166 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/events"
+)
+
+var ErrUnknownContentType = errors.New("unknown content type")
+
+// Codec converts events to and from their wire representation.
+type Codec interface {
+	ContentType() string
+	Marshal(event events.Message) ([]byte, error)
+	Unmarshal(data []byte, event *events.Message) error
+}
+
+type JSON struct{}
+
+func (JSON) ContentType() string {
+	return "application/json"
+}
+
+func (JSON) Marshal(event events.Message) ([]byte, error) {
+	return json.Marshal(event)
+}
+
+func (JSON) Unmarshal(data []byte, event *events.Message) error {
+	return json.Unmarshal(data, event)
+}
+
+var (
+	mu       sync.RWMutex
+	registry = map[string]Codec{}
+)
+
+func init() {
+	Register(JSON{})
+	Register(Msgpack{})
+	Register(CBOR{})
+	Register(Protobuf{})
+}
+
+// Register makes c available to Decode under its content type.
+func Register(c Codec) {
+	mu.Lock()
+	defer mu.Unlock()
+	registry[c.ContentType()] = c
+}
+
+// Lookup returns the codec registered for contentType.
+func Lookup(contentType string) (Codec, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	c, ok := registry[contentType]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownContentType, contentType)
+	}
+	return c, nil
+}
+
+// Named returns a built-in codec by its short name: json, msgpack, cbor or
+// protobuf.
+func Named(name string) (Codec, bool) {
+	switch name {
+	case "json":
+		return JSON{}, true
+	case "msgpack":
+		return Msgpack{}, true
+	case "cbor":
+		return CBOR{}, true
+	case "protobuf":
+		return Protobuf{}, true
+	}
+	return nil, false
+}
+
+// Encode marshals event with c and frames it with the content type, so
+// subscribers can decode it whatever codec they use themselves.
+func Encode(c Codec, event events.Message) ([]byte, error) {
+	body, err := c.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	return Frame{ContentType: c.ContentType(), Body: body}.Bytes(), nil
+}
+
+// Decode unmarshals a message produced by Encode, or plain JSON, with the
+// codec registered for its content type, decompressing it first if needed.
+func Decode(data []byte, event *events.Message) error {
+	return DecodeObserved(data, event, nil)
+}
+
+// DecodeObserved is Decode, telling observe, if not nil, the sizes of a
+// compressed message.
+func DecodeObserved(data []byte, event *events.Message, observe CompressionObserver) error {
+	f, err := ParseFrame(data)
+	if err != nil {
+		return err
+	}
+	return DecodeFrame(f, event, observe)
+}
+
+// DecodeFrame is DecodeObserved for a frame that has already been parsed.
+func DecodeFrame(f Frame, event *events.Message, observe CompressionObserver) (err error) {
+	if encoding := f.Encoding(); encoding != "" {
+		compressed := len(f.Body)
+		if f, err = f.Decompress(); err != nil {
+			return err
+		}
+		if observe != nil {
+			observe(encoding, compressed, len(f.Body))
+		}
+	}
+	c, err := Lookup(f.ContentType)
+	if err != nil {
+		return err
+	}
+	return c.Unmarshal(f.Body, event)
+}
+
+// plainPayload turns a payload still raw from another codec back into plain
+// Go values so it can be encoded again.
+func plainPayload(payload any) (any, error) {
+	switch raw := payload.(type) {
+	case json.RawMessage:
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.UseNumber()
+		var v any
+		if err := dec.Decode(&v); err != nil {
+			return nil, err
+		}
+		return numbers(v), nil
+	case events.PayloadDecoder:
+		var v any
+		err := raw.DecodePayload(&v)
+		return v, err
+	}
+	return payload, nil
+}
+
+// numbers replaces the json.Numbers in v with int64 where they are integers
+// and float64 otherwise, so binary codecs keep integers as integers.
+func numbers(v any) any {
+	switch v := v.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		f, _ := v.Float64()
+		return f
+	case map[string]any:
+		for k, e := range v {
+			v[k] = numbers(e)
+		}
+	case []any:
+		for i, e := range v {
+			v[i] = numbers(e)
+		}
+	}
+	return v
+}
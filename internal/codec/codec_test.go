@@ -0,0 +1,218 @@
+package codec
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/events"
+
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	c := JSON{}
+	in := events.Message{
+		ID:        "1",
+		Type:      "demo.message",
+		Source:    "test",
+		Timestamp: time.Date(2026, 2, 22, 10, 0, 0, 0, time.UTC),
+		Payload:   map[string]any{"text": "hello"},
+		Headers:   map[string]string{events.HeaderCorrelationID: "c-1", events.HeaderSchemaVersion: "2"},
+	}
+
+	data, err := c.Marshal(in)
+	if err != nil {
+		t.Fatalf("unexpected marshal error: %v", err)
+	}
+
+	var out events.Message
+	if err := c.Unmarshal(data, &out); err != nil {
+		t.Fatalf("unexpected unmarshal error: %v", err)
+	}
+	if out.ID != in.ID || out.Type != in.Type || out.Source != in.Source || !out.Timestamp.Equal(in.Timestamp) {
+		t.Fatalf("expected %+v, got %+v", in, out)
+	}
+	if out.CorrelationID() != "c-1" || out.SchemaVersion() != "2" {
+		t.Fatalf("expected headers to round-trip, got %v", out.Headers)
+	}
+	var payload map[string]any
+	if err := out.DecodePayload(&payload); err != nil {
+		t.Fatalf("unexpected payload decode error: %v", err)
+	}
+	if payload["text"] != "hello" {
+		t.Fatalf("expected payload to round-trip, got %v", out.Payload)
+	}
+}
+
+func TestJSONContentType(t *testing.T) {
+	if ct := (JSON{}).ContentType(); ct != "application/json" {
+		t.Fatalf("expected application/json, got %s", ct)
+	}
+}
+
+type testPayload struct {
+	Text  string   `json:"text"`
+	Count int      `json:"count"`
+	Tags  []string `json:"tags,omitempty"`
+}
+
+var allCodecs = []Codec{JSON{}, Msgpack{}, CBOR{}, Protobuf{}}
+
+func TestCodecsRoundTrip(t *testing.T) {
+	in := events.Message{
+		ID:        "1",
+		Type:      "demo.message",
+		Source:    "test",
+		Timestamp: time.Date(2026, 2, 22, 10, 0, 0, 123456789, time.UTC),
+		Key:       "k-1",
+		Headers:   map[string]string{events.HeaderCorrelationID: "c-1", events.HeaderTenantID: "acme"},
+		Payload:   testPayload{Text: "hello", Count: 3, Tags: []string{"a", "b"}},
+	}
+
+	for _, c := range allCodecs {
+		t.Run(c.ContentType(), func(t *testing.T) {
+			data, err := Encode(c, in)
+			if err != nil {
+				t.Fatalf("unexpected encode error: %v", err)
+			}
+			var out events.Message
+			if err := Decode(data, &out); err != nil {
+				t.Fatalf("unexpected decode error: %v", err)
+			}
+			if out.ID != in.ID || out.Type != in.Type || out.Source != in.Source || out.Key != in.Key || !out.Timestamp.Equal(in.Timestamp) {
+				t.Fatalf("expected %+v, got %+v", in, out)
+			}
+			if out.CorrelationID() != "c-1" || out.TenantID() != "acme" || len(out.Headers) != 2 {
+				t.Fatalf("expected headers to round-trip, got %v", out.Headers)
+			}
+			var payload testPayload
+			if err := out.DecodePayload(&payload); err != nil {
+				t.Fatalf("unexpected payload decode error: %v", err)
+			}
+			if payload.Text != "hello" || payload.Count != 3 || len(payload.Tags) != 2 {
+				t.Fatalf("expected payload to round-trip, got %+v", payload)
+			}
+		})
+	}
+}
+
+func TestCodecsWithoutPayload(t *testing.T) {
+	for _, c := range allCodecs {
+		data, err := Encode(c, events.Message{ID: "1"})
+		if err != nil {
+			t.Fatalf("%s: unexpected encode error: %v", c.ContentType(), err)
+		}
+		var out events.Message
+		if err := Decode(data, &out); err != nil {
+			t.Fatalf("%s: unexpected decode error: %v", c.ContentType(), err)
+		}
+		if out.ID != "1" || out.Payload != nil || out.Headers != nil || !out.Timestamp.IsZero() {
+			t.Fatalf("%s: expected an empty message, got %+v", c.ContentType(), out)
+		}
+	}
+}
+
+// TestCodecsReencodeRawPayloads checks that an event decoded with one codec
+// can be published with any other, as happens when the DLQ requeues it.
+func TestCodecsReencodeRawPayloads(t *testing.T) {
+	for _, from := range allCodecs {
+		data, err := Encode(from, events.Message{ID: "1", Payload: testPayload{Text: "hello", Count: 3}})
+		if err != nil {
+			t.Fatalf("unexpected encode error: %v", err)
+		}
+		var decoded events.Message
+		if err := Decode(data, &decoded); err != nil {
+			t.Fatalf("unexpected decode error: %v", err)
+		}
+
+		for _, to := range allCodecs {
+			data, err := Encode(to, decoded)
+			if err != nil {
+				t.Fatalf("%s -> %s: unexpected encode error: %v", from.ContentType(), to.ContentType(), err)
+			}
+			var out events.Message
+			if err := Decode(data, &out); err != nil {
+				t.Fatalf("%s -> %s: unexpected decode error: %v", from.ContentType(), to.ContentType(), err)
+			}
+			var payload testPayload
+			if err := out.DecodePayload(&payload); err != nil || payload.Text != "hello" || payload.Count != 3 {
+				t.Fatalf("%s -> %s: expected payload to survive, got %+v (%v)", from.ContentType(), to.ContentType(), payload, err)
+			}
+		}
+	}
+}
+
+func TestProtobufMessagePayload(t *testing.T) {
+	ts := timestamppb.New(time.Date(2026, 2, 22, 10, 0, 0, 0, time.UTC))
+	data, err := Encode(Protobuf{}, events.Message{ID: "1", Payload: ts})
+	if err != nil {
+		t.Fatalf("unexpected encode error: %v", err)
+	}
+	var out events.Message
+	if err := Decode(data, &out); err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+
+	var got timestamppb.Timestamp
+	if err := out.DecodePayload(&got); err != nil {
+		t.Fatalf("unexpected payload decode error: %v", err)
+	}
+	if !got.AsTime().Equal(ts.AsTime()) {
+		t.Fatalf("expected %v, got %v", ts.AsTime(), got.AsTime())
+	}
+	if err := out.DecodePayload(&durationpb.Duration{}); err == nil {
+		t.Fatal("expected decoding into another message type to fail")
+	}
+
+	// converted with protojson for JSON consumers
+	asJSON, err := json.Marshal(out.Payload)
+	if err != nil || string(asJSON) != `"2026-02-22T10:00:00Z"` {
+		t.Fatalf("expected protojson output, got %s (%v)", asJSON, err)
+	}
+}
+
+func TestFrame(t *testing.T) {
+	plain := []byte(`{"id":"1"}`)
+	f, err := ParseFrame(plain)
+	if err != nil || f.ContentType != "application/json" || string(f.Body) != string(plain) {
+		t.Fatalf("expected plain JSON, got %+v (%v)", f, err)
+	}
+	if string(f.Bytes()) != string(plain) {
+		t.Fatal("expected JSON without params to stay unframed")
+	}
+
+	framed := Frame{ContentType: "application/msgpack", Params: map[string]string{"encoding": "gzip"}, Body: []byte{0x80, '\n'}}.Bytes()
+	f, err = ParseFrame(framed)
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+	if f.ContentType != "application/msgpack" || f.Params["encoding"] != "gzip" || string(f.Body) != "\x80\n" {
+		t.Fatalf("unexpected frame %+v", f)
+	}
+
+	if _, err := ParseFrame([]byte("\x00application/json")); !errors.Is(err, ErrInvalidFrame) {
+		t.Fatalf("expected ErrInvalidFrame, got %v", err)
+	}
+	var event events.Message
+	if err := Decode([]byte("\x00application/xml\n<event/>"), &event); !errors.Is(err, ErrUnknownContentType) {
+		t.Fatalf("expected ErrUnknownContentType, got %v", err)
+	}
+}
+
+func TestNamed(t *testing.T) {
+	for _, name := range []string{"json", "msgpack", "cbor", "protobuf"} {
+		c, ok := Named(name)
+		if !ok {
+			t.Fatalf("expected codec %s", name)
+		}
+		if _, err := Lookup(c.ContentType()); err != nil {
+			t.Fatalf("expected %s to be registered: %v", name, err)
+		}
+	}
+	if _, ok := Named("xml"); ok {
+		t.Fatal("expected unknown codec name to be rejected")
+	}
+}
@@ -0,0 +1,127 @@
+package codec
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/klauspost/compress/gzip"
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Encodings understood in the encoding parameter of a frame.
+const (
+	Gzip   = "gzip"
+	Zstd   = "zstd"
+	Snappy = "snappy"
+)
+
+// MaxDecompressedSize bounds how large a compressed message may expand.
+const MaxDecompressedSize = 64 << 20
+
+var (
+	ErrUnknownEncoding = errors.New("unknown encoding")
+	ErrTooLarge        = errors.New("decompressed message too large")
+)
+
+// CompressionObserver is told the size of a message body before and after
+// compression.
+type CompressionObserver func(encoding string, compressed, original int)
+
+var zstdCodec = sync.OnceValues(func() (*zstd.Encoder, *zstd.Decoder) {
+	enc, _ := zstd.NewWriter(nil)
+	dec, _ := zstd.NewReader(nil, zstd.WithDecoderMaxMemory(MaxDecompressedSize))
+	return enc, dec
+})
+
+// Compress returns f with its body compressed with encoding, recorded in the
+// encoding parameter. f is returned unchanged when compressing does not make
+// the body smaller.
+func (f Frame) Compress(encoding string) (Frame, error) {
+	var body []byte
+	switch encoding {
+	case Gzip:
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(f.Body); err != nil {
+			return f, err
+		}
+		if err := w.Close(); err != nil {
+			return f, err
+		}
+		body = buf.Bytes()
+	case Zstd:
+		enc, _ := zstdCodec()
+		body = enc.EncodeAll(f.Body, nil)
+	case Snappy:
+		body = s2.EncodeSnappy(nil, f.Body)
+	default:
+		return f, fmt.Errorf("%w %q", ErrUnknownEncoding, encoding)
+	}
+	if len(body) >= len(f.Body) {
+		return f, nil
+	}
+	return f.withParam("encoding", encoding, body), nil
+}
+
+// Encoding returns the compression applied to the body, if any.
+func (f Frame) Encoding() string {
+	return f.Params["encoding"]
+}
+
+// Decompress reverses Compress. A frame without an encoding is returned as is.
+func (f Frame) Decompress() (Frame, error) {
+	var body []byte
+	var err error
+	switch encoding := f.Encoding(); encoding {
+	case "":
+		return f, nil
+	case Gzip:
+		var r *gzip.Reader
+		if r, err = gzip.NewReader(bytes.NewReader(f.Body)); err != nil {
+			return f, err
+		}
+		body, err = io.ReadAll(io.LimitReader(r, MaxDecompressedSize+1))
+		if err == nil && len(body) > MaxDecompressedSize {
+			err = ErrTooLarge
+		}
+	case Zstd:
+		_, dec := zstdCodec()
+		body, err = dec.DecodeAll(f.Body, nil)
+	case Snappy:
+		var n int
+		if n, err = s2.DecodedLen(f.Body); err == nil && n > MaxDecompressedSize {
+			err = ErrTooLarge
+		}
+		if err == nil {
+			body, err = s2.Decode(nil, f.Body)
+		}
+	default:
+		err = fmt.Errorf("%w %q", ErrUnknownEncoding, encoding)
+	}
+	if err != nil {
+		return f, err
+	}
+	return f.withParam("encoding", "", body), nil
+}
+
+// withParam returns a copy of f with a parameter set, or removed when value
+// is empty, and a new body.
+func (f Frame) withParam(key, value string, body []byte) Frame {
+	params := make(map[string]string, len(f.Params)+1)
+	for k, v := range f.Params {
+		params[k] = v
+	}
+	if value == "" {
+		delete(params, key)
+	} else {
+		params[key] = value
+	}
+	if len(params) == 0 {
+		params = nil
+	}
+	return Frame{ContentType: f.ContentType, Params: params, Body: body}
+}
@@ -0,0 +1,66 @@
+package codec
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/events"
+)
+
+func TestCompressRoundTrip(t *testing.T) {
+	event := events.Message{ID: "1", Type: "report.generated", Payload: map[string]any{"text": strings.Repeat("all work and no play ", 200)}}
+	for _, c := range []Codec{JSON{}, Msgpack{}} {
+		body, err := c.Marshal(event)
+		if err != nil {
+			t.Fatalf("unexpected marshal error: %v", err)
+		}
+		for _, encoding := range []string{Gzip, Zstd, Snappy} {
+			f, err := Frame{ContentType: c.ContentType(), Body: body}.Compress(encoding)
+			if err != nil {
+				t.Fatalf("%s: unexpected compress error: %v", encoding, err)
+			}
+			if f.Encoding() != encoding || len(f.Body) >= len(body) {
+				t.Fatalf("%s: expected a smaller compressed body, got %d of %d bytes", encoding, len(f.Body), len(body))
+			}
+
+			var observed []int
+			var decoded events.Message
+			err = DecodeObserved(f.Bytes(), &decoded, func(enc string, compressed, original int) {
+				observed = append(observed, compressed, original)
+			})
+			if err != nil {
+				t.Fatalf("%s: unexpected decode error: %v", encoding, err)
+			}
+			if decoded.ID != "1" || decoded.Type != "report.generated" {
+				t.Fatalf("%s: unexpected event %+v", encoding, decoded)
+			}
+			if len(observed) != 2 || observed[0] != len(f.Body) || observed[1] != len(body) {
+				t.Fatalf("%s: unexpected observed sizes %v", encoding, observed)
+			}
+		}
+	}
+}
+
+func TestCompressKeepsIncompressibleBody(t *testing.T) {
+	f := Frame{ContentType: "application/json", Body: []byte(`{"id":"1"}`)}
+	got, err := f.Compress(Gzip)
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	if got.Encoding() != "" || !bytes.Equal(got.Body, f.Body) {
+		t.Fatalf("expected the frame to stay uncompressed, got %+v", got)
+	}
+	if _, err := f.Compress("brotli"); !errors.Is(err, ErrUnknownEncoding) {
+		t.Fatalf("expected ErrUnknownEncoding, got %v", err)
+	}
+}
+
+func TestDecompressRejectsUnknownEncoding(t *testing.T) {
+	data := Frame{ContentType: "application/json", Params: map[string]string{"encoding": "brotli"}, Body: []byte("x")}.Bytes()
+	var event events.Message
+	if err := Decode(data, &event); !errors.Is(err, ErrUnknownEncoding) {
+		t.Fatalf("expected ErrUnknownEncoding, got %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+package codec
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"mime"
+)
+
+var ErrDecrypt = errors.New("message cannot be decrypted")
+
+// AESGCM is the only cipher in the encryption parameter of a frame.
+const AESGCM = "aes-gcm"
+
+// Encryption parameters of an encrypted frame.
+const (
+	paramEncryption = "encryption"
+	paramEncKeyID   = "ekid"
+)
+
+// Encrypt returns f with its body sealed with AES-GCM under key, which must be
+// 16, 24 or 32 bytes long. The key ID is carried in the ekid parameter. The
+// content type, encoding and key ID are authenticated along with the body.
+// Encrypt after compressing and before signing.
+func (f Frame) Encrypt(kid string, key []byte) (Frame, error) {
+	aead, err := newGCM(key)
+	if err != nil {
+		return f, err
+	}
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(f.Body)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return f, err
+	}
+	f = f.withParam(paramEncryption, AESGCM, f.Body)
+	f = f.withParam(paramEncKeyID, kid, f.Body)
+	f.Body = aead.Seal(nonce, nonce, f.Body, f.additionalData())
+	return f, nil
+}
+
+// Encrypted reports whether the body of f is encrypted.
+func (f Frame) Encrypted() bool {
+	_, ok := f.Params[paramEncryption]
+	return ok
+}
+
+// EncryptionKeyID returns the ID of the key the body was encrypted with.
+func (f Frame) EncryptionKeyID() string {
+	return f.Params[paramEncKeyID]
+}
+
+// Decrypt reverses Encrypt. A frame that is not encrypted is returned as is.
+func (f Frame) Decrypt(key []byte) (Frame, error) {
+	switch name := f.Params[paramEncryption]; name {
+	case "":
+		return f, nil
+	case AESGCM:
+	default:
+		return f, fmt.Errorf("%w: unknown cipher %q", ErrDecrypt, name)
+	}
+	aead, err := newGCM(key)
+	if err != nil {
+		return f, err
+	}
+	if len(f.Body) < aead.NonceSize() {
+		return f, fmt.Errorf("%w: body too short", ErrDecrypt)
+	}
+	nonce, sealed := f.Body[:aead.NonceSize()], f.Body[aead.NonceSize():]
+	body, err := aead.Open(nil, nonce, sealed, f.additionalData())
+	if err != nil {
+		return f, fmt.Errorf("%w: %w", ErrDecrypt, err)
+	}
+	f = f.withParam(paramEncryption, "", body)
+	return f.withParam(paramEncKeyID, "", body), nil
+}
+
+// additionalData binds the parameters needed to read the body to the
+// ciphertext. Signature parameters are added later and left out.
+func (f Frame) additionalData() []byte {
+	params := make(map[string]string, 3)
+	for _, k := range []string{"encoding", paramEncryption, paramEncKeyID} {
+		if v, ok := f.Params[k]; ok {
+			params[k] = v
+		}
+	}
+	return []byte(mime.FormatMediaType(f.ContentType, params))
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,57 @@
+package codec
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/events"
+)
+
+func TestEncryptRoundTrip(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	body, err := JSON{}.Marshal(events.Message{ID: "1", Type: "customer.updated", Payload: map[string]any{"email": strings.Repeat("jane@example.com ", 100)}})
+	if err != nil {
+		t.Fatalf("unexpected marshal error: %v", err)
+	}
+	f, err := Frame{ContentType: "application/json", Body: body}.Compress(Zstd)
+	if err != nil {
+		t.Fatalf("unexpected compress error: %v", err)
+	}
+	f, err = f.Encrypt("k1", key)
+	if err != nil {
+		t.Fatalf("unexpected encrypt error: %v", err)
+	}
+	f = f.Sign("s1", []byte("secret"))
+	if !f.Encrypted() || f.EncryptionKeyID() != "k1" || bytes.Contains(f.Bytes(), []byte("example.com")) {
+		t.Fatalf("expected an encrypted frame, got %q", f.Bytes())
+	}
+
+	parsed, err := ParseFrame(f.Bytes())
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+	plain, err := parsed.Decrypt(key)
+	if err != nil {
+		t.Fatalf("unexpected decrypt error: %v", err)
+	}
+	if plain.Encrypted() || plain.Encoding() != Zstd {
+		t.Fatalf("unexpected params %v", plain.Params)
+	}
+	var event events.Message
+	if err := DecodeFrame(plain, &event, nil); err != nil || event.Type != "customer.updated" {
+		t.Fatalf("unexpected event %+v (%v)", event, err)
+	}
+
+	if _, err := parsed.Decrypt(bytes.Repeat([]byte{2}, 32)); !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("expected ErrDecrypt for the wrong key, got %v", err)
+	}
+	relabelled := parsed.withParam("ekid", "k2", parsed.Body)
+	if _, err := relabelled.Decrypt(key); !errors.Is(err, ErrDecrypt) {
+		t.Fatalf("expected ErrDecrypt for a changed key ID, got %v", err)
+	}
+	if _, err := (Frame{Body: body}).Encrypt("k1", []byte("short")); err == nil {
+		t.Fatal("expected an invalid key size to be rejected")
+	}
+}
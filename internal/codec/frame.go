@@ -0,0 +1,57 @@
+package codec
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"mime"
+)
+
+var ErrInvalidFrame = errors.New("invalid frame")
+
+// frameMarker starts every framed message. JSON messages never start with a
+// NUL byte, so plain JSON from older publishers is still recognised.
+const frameMarker = 0x00
+
+// Frame is an encoded event with its media type. On the wire it is
+//
+//	\x00 <content-type>[; param=value]... \n <body>
+//
+// except for JSON without parameters, which is sent as the bare body.
+type Frame struct {
+	ContentType string
+	Params      map[string]string
+	Body        []byte
+}
+
+func (f Frame) Bytes() []byte {
+	if f.ContentType == (JSON{}).ContentType() && len(f.Params) == 0 {
+		return f.Body
+	}
+	header := mime.FormatMediaType(f.ContentType, f.Params)
+	buf := make([]byte, 0, len(header)+len(f.Body)+2)
+	buf = append(buf, frameMarker)
+	buf = append(buf, header...)
+	buf = append(buf, '\n')
+	return append(buf, f.Body...)
+}
+
+// ParseFrame splits data into its media type and body. Data without a frame
+// marker is taken to be plain JSON.
+func ParseFrame(data []byte) (Frame, error) {
+	if len(data) == 0 || data[0] != frameMarker {
+		return Frame{ContentType: (JSON{}).ContentType(), Body: data}, nil
+	}
+	end := bytes.IndexByte(data, '\n')
+	if end < 0 {
+		return Frame{}, fmt.Errorf("%w: missing header end", ErrInvalidFrame)
+	}
+	contentType, params, err := mime.ParseMediaType(string(data[1:end]))
+	if err != nil {
+		return Frame{}, fmt.Errorf("%w: %w", ErrInvalidFrame, err)
+	}
+	if len(params) == 0 {
+		params = nil
+	}
+	return Frame{ContentType: contentType, Params: params, Body: data[end+1:]}, nil
+}
@@ -0,0 +1,107 @@
+package codec
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/events"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Msgpack encodes events as MessagePack. Payload structs are encoded using
+// their json tags, so the same types work with every codec.
+type Msgpack struct{}
+
+type msgpackMessage struct {
+	ID        string             `msgpack:"id"`
+	Type      string             `msgpack:"type"`
+	Source    string             `msgpack:"source"`
+	Timestamp time.Time          `msgpack:"timestamp"`
+	Key       string             `msgpack:"key,omitempty"`
+	Headers   map[string]string  `msgpack:"headers,omitempty"`
+	Payload   msgpack.RawMessage `msgpack:"payload"`
+}
+
+func (Msgpack) ContentType() string {
+	return "application/msgpack"
+}
+
+func (Msgpack) Marshal(event events.Message) ([]byte, error) {
+	payload, err := msgpackPayload(event.Payload)
+	if err != nil {
+		return nil, err
+	}
+	return msgpackMarshal(msgpackMessage{
+		ID:        event.ID,
+		Type:      event.Type,
+		Source:    event.Source,
+		Timestamp: event.Timestamp,
+		Key:       event.Key,
+		Headers:   event.Headers,
+		Payload:   payload,
+	})
+}
+
+func (Msgpack) Unmarshal(data []byte, event *events.Message) error {
+	var m msgpackMessage
+	if err := msgpack.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	*event = events.Message{
+		ID:        m.ID,
+		Type:      m.Type,
+		Source:    m.Source,
+		Timestamp: m.Timestamp,
+		Key:       m.Key,
+		Headers:   m.Headers,
+	}
+	if len(m.Payload) > 0 && m.Payload[0] != msgpackNil {
+		event.Payload = MsgpackPayload(m.Payload)
+	}
+	return nil
+}
+
+const msgpackNil = 0xc0
+
+// MsgpackPayload is a payload still encoded as MessagePack.
+type MsgpackPayload []byte
+
+func (p MsgpackPayload) DecodePayload(v any) error {
+	dec := msgpack.NewDecoder(bytes.NewReader(p))
+	dec.SetCustomStructTag("json")
+	return dec.Decode(v)
+}
+
+// MarshalJSON converts the payload, so events decoded from MessagePack can be
+// stored or re-published as JSON.
+func (p MsgpackPayload) MarshalJSON() ([]byte, error) {
+	var v any
+	if err := p.DecodePayload(&v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+func msgpackPayload(payload any) (msgpack.RawMessage, error) {
+	if raw, ok := payload.(MsgpackPayload); ok {
+		return msgpack.RawMessage(raw), nil
+	}
+	v, err := plainPayload(payload)
+	if err != nil {
+		return nil, err
+	}
+	return msgpackMarshal(v)
+}
+
+func msgpackMarshal(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	enc.UseCompactInts(true)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,240 @@
+package codec
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/events"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/protowire"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// Protobuf encodes events as the broadcast.Event message in event.proto.
+// Payloads that are proto.Message values are embedded as protobuf; any other
+// payload is embedded as JSON.
+type Protobuf struct{}
+
+const (
+	pbID protowire.Number = iota + 1
+	pbType
+	pbSource
+	pbTimestamp
+	pbKey
+	pbHeaders
+	pbPayload
+	pbPayloadType
+)
+
+func (Protobuf) ContentType() string {
+	return "application/x-protobuf"
+}
+
+func (Protobuf) Marshal(event events.Message) ([]byte, error) {
+	payload, payloadType, err := protobufPayload(event.Payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var b []byte
+	b = appendString(b, pbID, event.ID)
+	b = appendString(b, pbType, event.Type)
+	b = appendString(b, pbSource, event.Source)
+	if !event.Timestamp.IsZero() {
+		var ts []byte
+		if s := event.Timestamp.Unix(); s != 0 {
+			ts = protowire.AppendTag(ts, 1, protowire.VarintType)
+			ts = protowire.AppendVarint(ts, uint64(s))
+		}
+		if n := event.Timestamp.Nanosecond(); n != 0 {
+			ts = protowire.AppendTag(ts, 2, protowire.VarintType)
+			ts = protowire.AppendVarint(ts, uint64(n))
+		}
+		b = protowire.AppendTag(b, pbTimestamp, protowire.BytesType)
+		b = protowire.AppendBytes(b, ts)
+	}
+	b = appendString(b, pbKey, event.Key)
+	keys := make([]string, 0, len(event.Headers))
+	for k := range event.Headers {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	for _, k := range keys {
+		var entry []byte
+		entry = appendString(entry, 1, k)
+		entry = appendString(entry, 2, event.Headers[k])
+		b = protowire.AppendTag(b, pbHeaders, protowire.BytesType)
+		b = protowire.AppendBytes(b, entry)
+	}
+	if payload != nil {
+		b = protowire.AppendTag(b, pbPayload, protowire.BytesType)
+		b = protowire.AppendBytes(b, payload)
+	}
+	b = appendString(b, pbPayloadType, payloadType)
+	return b, nil
+}
+
+func (Protobuf) Unmarshal(data []byte, event *events.Message) error {
+	*event = events.Message{}
+	var payload []byte
+	var payloadType string
+	var seconds, nanos int64
+	err := consumeFields(data, func(num protowire.Number, typ protowire.Type, value []byte, v uint64) error {
+		switch {
+		case num == pbID && typ == protowire.BytesType:
+			event.ID = string(value)
+		case num == pbType && typ == protowire.BytesType:
+			event.Type = string(value)
+		case num == pbSource && typ == protowire.BytesType:
+			event.Source = string(value)
+		case num == pbTimestamp && typ == protowire.BytesType:
+			return consumeFields(value, func(num protowire.Number, typ protowire.Type, _ []byte, v uint64) error {
+				switch {
+				case num == 1 && typ == protowire.VarintType:
+					seconds = int64(v)
+				case num == 2 && typ == protowire.VarintType:
+					nanos = int64(int32(v))
+				}
+				return nil
+			})
+		case num == pbKey && typ == protowire.BytesType:
+			event.Key = string(value)
+		case num == pbHeaders && typ == protowire.BytesType:
+			var k, val string
+			err := consumeFields(value, func(num protowire.Number, typ protowire.Type, value []byte, _ uint64) error {
+				switch {
+				case num == 1 && typ == protowire.BytesType:
+					k = string(value)
+				case num == 2 && typ == protowire.BytesType:
+					val = string(value)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			if event.Headers == nil {
+				event.Headers = make(map[string]string)
+			}
+			event.Headers[k] = val
+		case num == pbPayload && typ == protowire.BytesType:
+			payload = slices.Clone(value)
+		case num == pbPayloadType && typ == protowire.BytesType:
+			payloadType = string(value)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if seconds != 0 || nanos != 0 {
+		event.Timestamp = time.Unix(seconds, nanos).UTC()
+	}
+	switch {
+	case payloadType != "":
+		event.Payload = ProtoPayload{Type: payloadType, Data: payload}
+	case len(payload) > 0:
+		event.Payload = json.RawMessage(payload)
+	}
+	return nil
+}
+
+// ProtoPayload is a payload still encoded as the protobuf message Type.
+type ProtoPayload struct {
+	Type string
+	Data []byte
+}
+
+// DecodePayload decodes into v, which must be a proto.Message of the
+// payload's type, or into any other value when the type is linked into the
+// binary.
+func (p ProtoPayload) DecodePayload(v any) error {
+	if m, ok := v.(proto.Message); ok {
+		if name := string(m.ProtoReflect().Descriptor().FullName()); name != p.Type {
+			return fmt.Errorf("cannot decode %s payload into %s", p.Type, name)
+		}
+		return proto.Unmarshal(p.Data, m)
+	}
+	data, err := p.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// MarshalJSON converts the payload with protojson, which needs the payload
+// type to be linked into the binary.
+func (p ProtoPayload) MarshalJSON() ([]byte, error) {
+	mt, err := protoregistry.GlobalTypes.FindMessageByName(protoreflect.FullName(p.Type))
+	if err != nil {
+		return nil, err
+	}
+	m := mt.New().Interface()
+	if err := proto.Unmarshal(p.Data, m); err != nil {
+		return nil, err
+	}
+	return protojson.Marshal(m)
+}
+
+func protobufPayload(payload any) ([]byte, string, error) {
+	switch p := payload.(type) {
+	case nil:
+		return nil, "", nil
+	case ProtoPayload:
+		return p.Data, p.Type, nil
+	case proto.Message:
+		data, err := proto.Marshal(p)
+		return data, string(p.ProtoReflect().Descriptor().FullName()), err
+	case json.RawMessage:
+		return p, "", nil
+	}
+	data, err := json.Marshal(payload)
+	return data, "", err
+}
+
+func appendString(b []byte, num protowire.Number, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+	return protowire.AppendString(b, s)
+}
+
+var errInvalidProtobuf = errors.New("invalid protobuf")
+
+// consumeFields calls fn for every field in b with the bytes of a
+// length-delimited field or the value of a varint, skipping other types.
+func consumeFields(b []byte, fn func(num protowire.Number, typ protowire.Type, value []byte, v uint64) error) error {
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			return fmt.Errorf("%w: %w", errInvalidProtobuf, protowire.ParseError(n))
+		}
+		b = b[n:]
+
+		var value []byte
+		var v uint64
+		switch typ {
+		case protowire.BytesType:
+			value, n = protowire.ConsumeBytes(b)
+		case protowire.VarintType:
+			v, n = protowire.ConsumeVarint(b)
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, b)
+		}
+		if n < 0 {
+			return fmt.Errorf("%w: %w", errInvalidProtobuf, protowire.ParseError(n))
+		}
+		b = b[n:]
+		if err := fn(num, typ, value, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package codec
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/events"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/keyring"
+)
+
+// ErrNoSealKeys rejects sealing or opening an encrypted event without keys.
+var ErrNoSealKeys = errors.New("encrypted event but no encryption keys")
+
+// Seal encodes event as JSON for storage, such as a spill list or a
+// dead-letter queue. An event that was encrypted on the wire is encrypted
+// again under the current key of keys, so it is never stored in the clear;
+// keys may be nil if no event is encrypted.
+func Seal(event events.Message, keys *keyring.Keyring) ([]byte, error) {
+	body, err := JSON{}.Marshal(event)
+	if err != nil || !event.Encrypted {
+		return body, err
+	}
+	if keys == nil {
+		return nil, ErrNoSealKeys
+	}
+	kid, key, err := keys.Current()
+	if err != nil {
+		return nil, err
+	}
+	f, err := Frame{ContentType: JSON{}.ContentType(), Body: body}.Encrypt(kid, key)
+	if err != nil {
+		return nil, err
+	}
+	return f.Bytes(), nil
+}
+
+// Open decodes data written by Seal into event, decrypting it with keys. An
+// event that was sealed encrypted is marked Encrypted again.
+func Open(data []byte, keys *keyring.Keyring, event *events.Message) error {
+	f, err := ParseFrame(data)
+	if err != nil {
+		return err
+	}
+	if f.Encrypted() {
+		if keys == nil {
+			return ErrNoSealKeys
+		}
+		key, err := keys.Key(f.EncryptionKeyID())
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrDecrypt, err)
+		}
+		if f, err = f.Decrypt(key); err != nil {
+			return err
+		}
+		event.Encrypted = true
+	}
+	return DecodeFrame(f, event, nil)
+}
@@ -0,0 +1,45 @@
+package codec
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/events"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/keyring"
+)
+
+func TestSeal(t *testing.T) {
+	keys := keyring.New()
+	keys.Add("k1", bytes.Repeat([]byte{1}, 32))
+
+	plain := events.Message{ID: "1", Type: "order.created", Payload: "public"}
+	data, err := Seal(plain, nil)
+	if err != nil || !bytes.Contains(data, []byte("public")) {
+		t.Fatalf("expected plain JSON for an event that was not encrypted, got %q (%v)", data, err)
+	}
+
+	secret := events.Message{ID: "2", Type: "customer.updated", Payload: "jane@example.com", Encrypted: true}
+	if _, err := Seal(secret, nil); !errors.Is(err, ErrNoSealKeys) {
+		t.Fatalf("expected ErrNoSealKeys without keys, got %v", err)
+	}
+	data, err = Seal(secret, keys)
+	if err != nil {
+		t.Fatalf("unexpected seal error: %v", err)
+	}
+	if bytes.Contains(data, []byte("example.com")) {
+		t.Fatalf("expected the event to be encrypted, got %q", data)
+	}
+
+	var opened events.Message
+	if err := Open(data, keys, &opened); err != nil {
+		t.Fatalf("unexpected open error: %v", err)
+	}
+	var payload string
+	if err := opened.DecodePayload(&payload); err != nil || payload != "jane@example.com" || !opened.Encrypted {
+		t.Fatalf("expected the encrypted event back, got %+v (%v)", opened, err)
+	}
+	if err := Open(data, nil, &opened); !errors.Is(err, ErrNoSealKeys) {
+		t.Fatalf("expected ErrNoSealKeys opening without keys, got %v", err)
+	}
+}
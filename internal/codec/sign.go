@@ -0,0 +1,59 @@
+package codec
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"mime"
+)
+
+// ErrBadSignature is returned by Frame.Verify when the signature does not
+// match the frame.
+var ErrBadSignature = errors.New("bad signature")
+
+// Signature parameters of a signed frame.
+const (
+	paramKeyID     = "kid"
+	paramSignature = "sig"
+)
+
+// Sign returns f signed with HMAC-SHA256 under key. The key ID and signature
+// are carried in the kid and sig parameters; the signature covers the content
+// type, every other parameter and the body, so it must be applied after
+// compression.
+func (f Frame) Sign(kid string, key []byte) Frame {
+	f = f.withParam(paramKeyID, kid, f.Body)
+	return f.withParam(paramSignature, base64.RawURLEncoding.EncodeToString(f.mac(key)), f.Body)
+}
+
+// KeyID returns the ID of the key f was signed with, or an empty string if it
+// is unsigned.
+func (f Frame) KeyID() string {
+	return f.Params[paramKeyID]
+}
+
+// Signed reports whether f carries a signature.
+func (f Frame) Signed() bool {
+	_, ok := f.Params[paramSignature]
+	return ok
+}
+
+// Verify checks the signature of f against key.
+func (f Frame) Verify(key []byte) error {
+	sig, err := base64.RawURLEncoding.DecodeString(f.Params[paramSignature])
+	if err != nil || !hmac.Equal(sig, f.mac(key)) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// mac hashes the frame as it is written on the wire, without the signature.
+func (f Frame) mac(key []byte) []byte {
+	unsigned := f.withParam(paramSignature, "", nil)
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(mime.FormatMediaType(unsigned.ContentType, unsigned.Params)))
+	h.Write([]byte{'\n'})
+	h.Write(f.Body)
+	return h.Sum(nil)
+}
@@ -0,0 +1,35 @@
+package codec
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSignAndVerify(t *testing.T) {
+	key := []byte("secret")
+	f := Frame{ContentType: "application/json", Params: map[string]string{"encoding": "zstd"}, Body: []byte("body")}.Sign("k1", key)
+	if !f.Signed() || f.KeyID() != "k1" {
+		t.Fatalf("expected a signed frame, got %+v", f)
+	}
+
+	parsed, err := ParseFrame(f.Bytes())
+	if err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+	if err := parsed.Verify(key); err != nil {
+		t.Fatalf("expected signature to verify, got %v", err)
+	}
+	if err := parsed.Verify([]byte("other")); !errors.Is(err, ErrBadSignature) {
+		t.Fatalf("expected ErrBadSignature for the wrong key, got %v", err)
+	}
+
+	tampered := parsed
+	tampered.Body = []byte("BODY")
+	if err := tampered.Verify(key); !errors.Is(err, ErrBadSignature) {
+		t.Fatalf("expected ErrBadSignature for a changed body, got %v", err)
+	}
+	stripped := parsed.withParam("encoding", "", parsed.Body)
+	if err := stripped.Verify(key); !errors.Is(err, ErrBadSignature) {
+		t.Fatalf("expected ErrBadSignature for changed params, got %v", err)
+	}
+}
@@ -0,0 +1,54 @@
+// Package dedup remembers which events have been handled so duplicates, from
+// publisher retries or at-least-once transports, can be skipped.
+package dedup
+
+import (
+	"context"
+	"time"
+)
+
+// State is what Claim found for an ID.
+type State int
+
+const (
+	// Claimed means the caller now holds the claim and should handle the
+	// event.
+	Claimed State = iota
+	// InProgress means another claim on the ID is held and not settled yet.
+	// The event may still fail, so it must not be treated as handled.
+	InProgress
+	// Done means the event has been handled.
+	Done
+)
+
+func (s State) String() string {
+	switch s {
+	case Claimed:
+		return "claimed"
+	case InProgress:
+		return "in-progress"
+	case Done:
+		return "done"
+	default:
+		return "unknown"
+	}
+}
+
+// Store records event IDs. A Memory store deduplicates per process, a Redis
+// store shared by all subscribers deduplicates across the fleet.
+//
+// An ID is first claimed for processing, for a short ttl so the claim of a
+// node that crashed expires, and only marked done once the event has been
+// handled, like the claims of dispatcher.Singleton.
+type Store interface {
+	// Claim takes a processing claim on id for ttl. The token it returns
+	// with Claimed releases the claim.
+	Claim(ctx context.Context, id string, ttl time.Duration) (token string, state State, err error)
+	// Complete marks id as done for the store's TTL if the claim is still
+	// held with token. A claim that expired may belong to another node by
+	// now, which completes or releases it itself.
+	Complete(ctx context.Context, id, token string) error
+	// Release gives up a processing claim held with token, so a redelivery
+	// of an event that failed is handled again.
+	Release(ctx context.Context, id, token string) error
+}
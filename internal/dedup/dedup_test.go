@@ -0,0 +1,133 @@
+package dedup
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func claim(t *testing.T, s Store, id string, ttl time.Duration) (string, State) {
+	t.Helper()
+	token, state, err := s.Claim(context.Background(), id, ttl)
+	if err != nil {
+		t.Fatalf("unexpected claim error: %v", err)
+	}
+	return token, state
+}
+
+func complete(t *testing.T, s Store, id, token string) {
+	t.Helper()
+	if err := s.Complete(context.Background(), id, token); err != nil {
+		t.Fatalf("unexpected complete error: %v", err)
+	}
+}
+
+func release(t *testing.T, s Store, id, token string) {
+	t.Helper()
+	if err := s.Release(context.Background(), id, token); err != nil {
+		t.Fatalf("unexpected release error: %v", err)
+	}
+}
+
+func TestMemory(t *testing.T) {
+	m := NewMemory(2, time.Minute)
+	now := time.Now()
+	m.now = func() time.Time { return now }
+
+	token, state := claim(t, m, "a", time.Second)
+	if state != Claimed {
+		t.Fatalf("expected the first claim to succeed, got %s", state)
+	}
+	if _, state := claim(t, m, "a", time.Second); state != InProgress {
+		t.Fatalf("expected an unsettled claim to be in progress, got %s", state)
+	}
+	release(t, m, "a", "not-the-token")
+	if _, state := claim(t, m, "a", time.Second); state != InProgress {
+		t.Fatalf("expected a release with the wrong token to be ignored, got %s", state)
+	}
+	release(t, m, "a", token)
+	if _, state := claim(t, m, "a", time.Second); state != Claimed {
+		t.Fatalf("expected a released ID to be claimable again, got %s", state)
+	}
+
+	// the processing claim of a crashed node expires long before the TTL
+	now = now.Add(time.Second)
+	token, state = claim(t, m, "a", time.Second)
+	if state != Claimed {
+		t.Fatalf("expected an expired processing claim to be claimable again, got %s", state)
+	}
+	complete(t, m, "a", "not-the-token")
+	if _, state := claim(t, m, "a", time.Second); state != InProgress {
+		t.Fatalf("expected a complete with the wrong token to be ignored, got %s", state)
+	}
+	complete(t, m, "a", token)
+	now = now.Add(30 * time.Second)
+	if _, state := claim(t, m, "a", time.Second); state != Done {
+		t.Fatalf("expected a completed ID to be done for the TTL, got %s", state)
+	}
+
+	// capacity evicts the least recently used ID
+	claim(t, m, "b", time.Minute)
+	if _, state := claim(t, m, "a", time.Second); state != Done {
+		t.Fatalf("expected a to still be done, got %s", state)
+	}
+	claim(t, m, "c", time.Minute)
+	if _, state := claim(t, m, "a", time.Second); state != Done {
+		t.Fatalf("expected the duplicate hit to keep a, got %s", state)
+	}
+	if _, state := claim(t, m, "b", time.Minute); m.Len() != 2 || state != Claimed {
+		t.Fatalf("expected b to be evicted, holding %d IDs", m.Len())
+	}
+
+	now = now.Add(time.Minute)
+	if _, state := claim(t, m, "c", time.Minute); state != Claimed {
+		t.Fatalf("expected an expired ID to be claimable again, got %s", state)
+	}
+	if m.Len() != 1 {
+		t.Fatalf("expected expired IDs to be evicted, holding %d", m.Len())
+	}
+}
+
+func TestRedis(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	node1 := NewRedis(client, "dedup:", time.Minute)
+	node2 := NewRedis(client, "dedup:", time.Minute)
+
+	token, state := claim(t, node1, "a", time.Second)
+	if state != Claimed {
+		t.Fatalf("expected the first claim to succeed, got %s", state)
+	}
+	if _, state := claim(t, node2, "a", time.Second); state != InProgress {
+		t.Fatalf("expected the claim to be shared across stores with the same prefix, got %s", state)
+	}
+	release(t, node2, "a", "not-the-token")
+	release(t, node1, "a", token)
+	if _, state := claim(t, node2, "a", time.Second); state != Claimed {
+		t.Fatalf("expected a released ID to be claimable again, got %s", state)
+	}
+
+	mr.FastForward(time.Second)
+	token, state = claim(t, node1, "a", time.Second)
+	if state != Claimed {
+		t.Fatalf("expected an expired processing claim to be claimable again, got %s", state)
+	}
+	complete(t, node2, "a", "not-the-token")
+	if _, state := claim(t, node2, "a", time.Second); state != InProgress {
+		t.Fatalf("expected a complete with the wrong token to be ignored, got %s", state)
+	}
+	complete(t, node1, "a", token)
+	if ttl := mr.TTL("dedup:a"); ttl != time.Minute {
+		t.Fatalf("expected a one minute TTL, got %s", ttl)
+	}
+	if _, state := claim(t, node2, "a", time.Second); state != Done {
+		t.Fatalf("expected a completed ID to be done, got %s", state)
+	}
+	mr.FastForward(time.Minute)
+	if _, state := claim(t, node1, "a", time.Second); state != Claimed {
+		t.Fatalf("expected an expired ID to be claimable again, got %s", state)
+	}
+}
@@ -0,0 +1,114 @@
+package dedup
+
+import (
+	"container/list"
+	"context"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Memory is an in-process Store holding up to capacity IDs for ttl each.
+// When full, the least recently claimed, completed or seen as a duplicate ID
+// is forgotten first.
+type Memory struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	order    *list.List // of *entry, most recently used at the front
+	entries  map[string]*list.Element
+	tokens   int64
+	now      func() time.Time
+}
+
+type entry struct {
+	id      string
+	token   string
+	done    bool
+	expires time.Time
+}
+
+func NewMemory(capacity int, ttl time.Duration) *Memory {
+	return &Memory{
+		capacity: max(1, capacity),
+		ttl:      ttl,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+		now:      time.Now,
+	}
+}
+
+func (m *Memory) Claim(ctx context.Context, id string, ttl time.Duration) (string, State, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	if el, ok := m.entries[id]; ok {
+		if e := el.Value.(*entry); now.Before(e.expires) {
+			m.order.MoveToFront(el)
+			if e.done {
+				return "", Done, nil
+			}
+			return "", InProgress, nil
+		}
+		m.remove(el)
+	}
+	m.evictExpired(now)
+	m.tokens++
+	token := strconv.FormatInt(m.tokens, 10)
+	m.put(&entry{id: id, token: token, expires: now.Add(ttl)})
+	return token, Claimed, nil
+}
+
+func (m *Memory) Complete(ctx context.Context, id, token string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	el, ok := m.entries[id]
+	if !ok {
+		return nil
+	}
+	if e := el.Value.(*entry); e.done || e.token != token || !m.now().Before(e.expires) {
+		return nil
+	}
+	m.remove(el)
+	m.put(&entry{id: id, done: true, expires: m.now().Add(m.ttl)})
+	return nil
+}
+
+func (m *Memory) Release(ctx context.Context, id, token string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if el, ok := m.entries[id]; ok {
+		if e := el.Value.(*entry); !e.done && e.token == token {
+			m.remove(el)
+		}
+	}
+	return nil
+}
+
+// Len returns the number of IDs held, including expired ones not evicted yet.
+func (m *Memory) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.order.Len()
+}
+
+func (m *Memory) put(e *entry) {
+	m.entries[e.id] = m.order.PushFront(e)
+	for m.order.Len() > m.capacity {
+		m.remove(m.order.Back())
+	}
+}
+
+// evictExpired drops expired IDs from the back. Processing claims are
+// shorter than the TTL and hits move IDs to the front, so some expired IDs
+// may be left until they reach it.
+func (m *Memory) evictExpired(now time.Time) {
+	for el := m.order.Back(); el != nil && !now.Before(el.Value.(*entry).expires); el = m.order.Back() {
+		m.remove(el)
+	}
+}
+
+func (m *Memory) remove(el *list.Element) {
+	m.order.Remove(el)
+	delete(m.entries, el.Value.(*entry).id)
+}
@@ -0,0 +1,76 @@
+package dedup
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// done is stored under an ID whose event has been handled.
+const done = "done"
+
+var (
+	claimScript = redis.NewScript(`
+if redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+	return 0
+end
+if redis.call("GET", KEYS[1]) == ARGV[3] then
+	return 2
+end
+return 1`)
+	completeScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("SET", KEYS[1], ARGV[2], "PX", ARGV[3])
+end
+return false`)
+	releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+)
+
+// Redis is a Store shared by every subscriber using the same prefix, so an
+// event is handled once per fleet. An ID is claimed with SET NX PX to a random
+// token and overwritten with "done" for ttl once handled.
+type Redis struct {
+	client *redis.Client
+	prefix string
+	ttl    time.Duration
+}
+
+func NewRedis(client *redis.Client, prefix string, ttl time.Duration) *Redis {
+	return &Redis{client: client, prefix: prefix, ttl: ttl}
+}
+
+func (r *Redis) Claim(ctx context.Context, id string, ttl time.Duration) (string, State, error) {
+	token := uuid.NewString()
+	state, err := claimScript.Run(ctx, r.client, []string{r.prefix + id}, token, ttl.Milliseconds(), done).Int()
+	if err != nil {
+		return "", 0, err
+	}
+	if State(state) != Claimed {
+		return "", State(state), nil
+	}
+	return token, Claimed, nil
+}
+
+func (r *Redis) Complete(ctx context.Context, id, token string) error {
+	return r.CompleteFor(ctx, id, token, r.ttl)
+}
+
+// CompleteFor is Complete with ttl instead of the store's TTL.
+func (r *Redis) CompleteFor(ctx context.Context, id, token string, ttl time.Duration) error {
+	err := completeScript.Run(ctx, r.client, []string{r.prefix + id}, token, done, ttl.Milliseconds()).Err()
+	if errors.Is(err, redis.Nil) {
+		return nil // the claim expired and may belong to another node now
+	}
+	return err
+}
+
+func (r *Redis) Release(ctx context.Context, id, token string) error {
+	return releaseScript.Run(ctx, r.client, []string{r.prefix + id}, token).Err()
+}
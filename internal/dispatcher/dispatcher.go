@@ -2,49 +2,281 @@ package dispatcher
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
+	"sort"
+	"strings"
 	"sync"
 
 	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/events"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Handler interface {
 	Handle(ctx context.Context, event events.Message) error
 }
 
+// HandlerFunc adapts a plain function to the Handler interface.
+type HandlerFunc func(ctx context.Context, event events.Message) error
+
+func (f HandlerFunc) Handle(ctx context.Context, event events.Message) error {
+	return f(ctx, event)
+}
+
+type namedHandler struct {
+	name      string
+	handler   Handler
+	singleton *singleton
+}
+
 type Dispatcher struct {
-	mu       sync.RWMutex
-	handlers map[string]Handler
-	logger   *slog.Logger
+	mu         sync.RWMutex
+	handlers   map[string][]namedHandler
+	middleware []Middleware
+	typed      map[string][]Middleware
+	fanOut     FanOutPolicy
+	fanOutFor  map[string]FanOutPolicy
+	fallback   Handler
+	logger     *slog.Logger
+
+	routeMu sync.Mutex
+	routes  map[string]route
 }
 
 func New(logger *slog.Logger) *Dispatcher {
 	return &Dispatcher{
-		handlers: make(map[string]Handler),
-		logger:   logger,
+		handlers:  make(map[string][]namedHandler),
+		typed:     make(map[string][]Middleware),
+		fanOutFor: make(map[string]FanOutPolicy),
+		routes:    make(map[string]route),
+		logger:    logger,
 	}
 }
 
-func (d *Dispatcher) Register(eventType string, handler Handler) {
+// Use adds middleware that wraps the handlers of every event type. Middleware
+// added first runs outermost.
+func (d *Dispatcher) Use(mw ...Middleware) {
 	d.mu.Lock()
 	defer d.mu.Unlock()
-	d.handlers[eventType] = handler
-	d.logger.Info("handler registered", "event_type", eventType)
+	d.middleware = append(d.middleware, mw...)
+}
+
+// UseFor adds middleware that only wraps the handlers registered under
+// eventType, which may be a pattern. It runs inside any middleware added with
+// Use.
+func (d *Dispatcher) UseFor(eventType string, mw ...Middleware) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.typed[eventType] = append(d.typed[eventType], mw...)
+}
+
+// SetFanOut sets how the handlers of an event type are run when there is no
+// per-type policy. The default is Sequential.
+func (d *Dispatcher) SetFanOut(policy FanOutPolicy) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.fanOut = policy
+}
+
+// SetFanOutFor sets how the handlers registered under eventType, which may be
+// a pattern, are run.
+func (d *Dispatcher) SetFanOutFor(eventType string, policy FanOutPolicy) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.fanOutFor[eventType] = policy
+}
+
+type RegisterOption func(*namedHandler)
+
+// WithName names a handler. Registering another handler under the same name
+// for the same event type replaces it.
+func WithName(name string) RegisterOption {
+	return func(h *namedHandler) { h.name = name }
+}
+
+// Register adds handler to the handlers of eventType, which may be a pattern
+// such as "order.*" or "order.#". Without WithName the handler is named after
+// its Go type, with a numeric suffix if that name is already taken.
+func (d *Dispatcher) Register(eventType string, handler Handler, opts ...RegisterOption) {
+	nh := namedHandler{handler: handler}
+	for _, opt := range opts {
+		opt(&nh)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.clearRoutes()
+	existing := d.handlers[eventType]
+	if nh.name == "" {
+		nh.name = uniqueName(existing, fmt.Sprintf("%T", handler))
+	}
+	for i, h := range existing {
+		if h.name == nh.name {
+			existing[i] = nh
+			d.logger.Info("handler replaced", "event_type", eventType, "handler", nh.name)
+			return
+		}
+	}
+	d.handlers[eventType] = append(existing, nh)
+	d.logger.Info("handler registered", "event_type", eventType, "handler", nh.name)
+}
+
+// HandlerType returns the comma-separated names of the handlers eventType is
+// routed to, or an empty string if there are none.
+func (d *Dispatcher) HandlerType(eventType string) string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	key, ok := d.resolve(eventType)
+	if !ok {
+		if d.fallback != nil {
+			return FallbackName
+		}
+		return ""
+	}
+	names := make([]string, 0, len(d.handlers[key]))
+	for _, h := range d.handlers[key] {
+		names = append(names, h.name)
+	}
+	return strings.Join(names, ",")
+}
+
+// Route returns the registration key eventType is routed to: the event type
+// itself or the pattern that matches it. ok is false when no handler is
+// registered for it.
+func (d *Dispatcher) Route(eventType string) (key string, ok bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.resolve(eventType)
+}
+
+// EventTypes returns the event types and patterns handlers are registered
+// for, sorted.
+func (d *Dispatcher) EventTypes() []string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	types := make([]string, 0, len(d.handlers))
+	for eventType, handlers := range d.handlers {
+		if len(handlers) > 0 {
+			types = append(types, eventType)
+		}
+	}
+	sort.Strings(types)
+	return types
 }
 
 func (d *Dispatcher) Dispatch(ctx context.Context, event events.Message) error {
+	return d.DispatchHandlers(ctx, event, nil)
+}
+
+// SetFallback sets a handler for events whose type matches no registration.
+// Without one, such events are logged and dropped.
+func (d *Dispatcher) SetFallback(handler Handler) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.fallback = handler
+}
+
+// DispatchHandlers runs only the named handlers the event is routed to, or
+// all of them when names is nil. Failures are reported as a *DispatchError.
+func (d *Dispatcher) DispatchHandlers(ctx context.Context, event events.Message, names []string) error {
 	d.mu.RLock()
-	handler, ok := d.handlers[event.Type]
+	key, ok := d.resolve(event.Type)
+	var registered []namedHandler
+	if ok {
+		registered = d.handlers[key]
+	} else if d.fallback != nil {
+		registered = []namedHandler{{name: FallbackName, handler: d.fallback}}
+	}
+	policy, found := d.fanOutFor[key]
+	if !found {
+		policy = d.fanOut
+	}
+	selected := make([]namedHandler, 0, len(registered))
+	for _, h := range registered {
+		if names != nil && !contains(names, h.name) {
+			continue
+		}
+		var mw []Middleware
+		if ok {
+			mw = d.typed[key]
+		}
+		global := d.middleware
+		wrap := func(h Handler) Handler { return chain(chain(h, mw), global) }
+		if h.singleton != nil {
+			h.handler = h.singleton.guard(d, h.name, h.handler, wrap)
+		} else {
+			h.handler = wrap(h.handler)
+		}
+		h.handler = traced(h.name, h.handler)
+		selected = append(selected, h)
+	}
 	d.mu.RUnlock()
 
-	if !ok {
+	if len(registered) == 0 {
 		d.logger.Warn("no handler found", "event_type", event.Type)
 		return nil // nothing to do
 	}
 
-	if err := handler.Handle(ctx, event); err != nil {
-		d.logger.Error("handler failed", "event_type", event.Type, "error", err)
+	err := fanOut(ctx, policy, selected, event)
+	if err != nil {
+		for _, f := range err.Failures {
+			d.logger.Error("handler failed", "event_type", event.Type, "handler", f.Handler, "error", event.Redact(f.Err))
+		}
 		return err
 	}
 	return nil
 }
+
+type handlerNameKey struct{}
+
+// HandlerName returns the name of the handler ctx was dispatched to, for
+// middleware that reports per handler. It is empty outside a dispatch.
+func HandlerName(ctx context.Context) string {
+	name, _ := ctx.Value(handlerNameKey{}).(string)
+	return name
+}
+
+// traced runs handler inside a span named after it, so every handler attempt
+// shows up in the trace, and records its name for HandlerName.
+func traced(name string, handler Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, event events.Message) error {
+		ctx = context.WithValue(ctx, handlerNameKey{}, name)
+		ctx, span := tracing.Tracer().Start(ctx, "handle "+name,
+			tracing.Attributes(event),
+			trace.WithAttributes(attribute.String("handler", name)),
+		)
+		err := handler.Handle(ctx, event)
+		tracing.EndEvent(span, event, err)
+		return err
+	})
+}
+
+func uniqueName(existing []namedHandler, base string) string {
+	name := base
+	for n := 2; ; n++ {
+		taken := false
+		for _, h := range existing {
+			if h.name == name {
+				taken = true
+				break
+			}
+		}
+		if !taken {
+			return name
+		}
+		name = fmt.Sprintf("%s#%d", base, n)
+	}
+}
+
+func contains(values []string, v string) bool {
+	for _, s := range values {
+		if s == v {
+			return true
+		}
+	}
+	return false
+}
@@ -48,7 +48,8 @@ func TestRegisterHandler(t *testing.T) {
 
 	dispatcher.Register(eventType, handler)
 
-	if dispatcher.handlers[eventType] != handler {
+	registered := dispatcher.handlers[eventType]
+	if len(registered) != 1 || registered[0].handler != handler {
 		t.Fatalf("expected handler to be registered for event type %s", eventType)
 	}
 
@@ -108,3 +109,44 @@ func TestDispatchHandlerError(t *testing.T) {
 		t.Fatal("expected dispatch to return error from handler, got nil")
 	}
 }
+
+func TestHandlerType(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	dispatcher := New(logger)
+	dispatcher.Register("test_event", &mockHandler{})
+
+	if got := dispatcher.HandlerType("test_event"); got != "*dispatcher.mockHandler" {
+		t.Fatalf("expected *dispatcher.mockHandler, got %q", got)
+	}
+	if got := dispatcher.HandlerType("unregistered_event"); got != "" {
+		t.Fatalf("expected empty handler type, got %q", got)
+	}
+}
+
+func TestRoute(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	dispatcher := New(logger)
+	dispatcher.Register("orders.*", &mockHandler{})
+	dispatcher.Register("demo.message", &mockHandler{})
+
+	for eventType, want := range map[string]string{"demo.message": "demo.message", "orders.created": "orders.*"} {
+		if key, ok := dispatcher.Route(eventType); !ok || key != want {
+			t.Fatalf("expected %s to route to %q, got %q", eventType, want, key)
+		}
+	}
+	if _, ok := dispatcher.Route("unregistered_event"); ok {
+		t.Fatal("expected no route for an unregistered event type")
+	}
+}
+
+func TestEventTypes(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	dispatcher := New(logger)
+	dispatcher.Register("orders.*", &mockHandler{})
+	dispatcher.Register("demo.message", &mockHandler{})
+
+	got := dispatcher.EventTypes()
+	if len(got) != 2 || got[0] != "demo.message" || got[1] != "orders.*" {
+		t.Fatalf("expected [demo.message orders.*], got %v", got)
+	}
+}
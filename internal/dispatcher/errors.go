@@ -0,0 +1,29 @@
+package dispatcher
+
+import "errors"
+
+// ErrPermanent marks handler errors that retrying cannot fix, such as
+// validation failures. The processor does not retry them.
+var ErrPermanent = errors.New("permanent failure")
+
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+
+func (e permanentError) Unwrap() error { return e.err }
+
+func (e permanentError) Is(target error) bool { return target == ErrPermanent }
+
+// Permanent wraps err so that IsPermanent reports true for it.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return permanentError{err: err}
+}
+
+func IsPermanent(err error) bool {
+	return errors.Is(err, ErrPermanent)
+}
@@ -0,0 +1,34 @@
+package dispatcher
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestPermanent(t *testing.T) {
+	cause := errors.New("invalid payload")
+	err := Permanent(cause)
+
+	if !IsPermanent(err) {
+		t.Fatal("expected wrapped error to be permanent")
+	}
+	if !errors.Is(err, cause) {
+		t.Fatal("expected wrapped error to unwrap to its cause")
+	}
+	if err.Error() != "invalid payload" {
+		t.Fatalf("expected message of the cause, got %q", err.Error())
+	}
+	if !IsPermanent(fmt.Errorf("handler: %w", err)) {
+		t.Fatal("expected permanence to survive further wrapping")
+	}
+	if !IsPermanent(fmt.Errorf("%w: missing field", ErrPermanent)) {
+		t.Fatal("expected errors wrapping the sentinel to be permanent")
+	}
+	if IsPermanent(cause) {
+		t.Fatal("expected plain error not to be permanent")
+	}
+	if Permanent(nil) != nil {
+		t.Fatal("expected Permanent(nil) to be nil")
+	}
+}
@@ -0,0 +1,125 @@
+package dispatcher
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/events"
+)
+
+// FanOutPolicy decides how the handlers registered for one event type run.
+type FanOutPolicy int
+
+const (
+	// Sequential runs every handler in registration order, even after a
+	// failure.
+	Sequential FanOutPolicy = iota
+	// Parallel runs every handler concurrently.
+	Parallel
+	// FailFast runs handlers in registration order and stops at the first
+	// failure; the remaining handlers are reported as skipped.
+	FailFast
+)
+
+func (p FanOutPolicy) String() string {
+	switch p {
+	case Sequential:
+		return "sequential"
+	case Parallel:
+		return "parallel"
+	case FailFast:
+		return "fail-fast"
+	default:
+		return fmt.Sprintf("FanOutPolicy(%d)", int(p))
+	}
+}
+
+// HandlerError is the failure of a single named handler.
+type HandlerError struct {
+	Handler string
+	Err     error
+}
+
+func (e *HandlerError) Error() string {
+	return e.Handler + ": " + e.Err.Error()
+}
+
+func (e *HandlerError) Unwrap() error {
+	return e.Err
+}
+
+// DispatchError reports which handlers of an event failed, and which were
+// not run because an earlier one failed under FailFast.
+type DispatchError struct {
+	EventType string
+	Failures  []*HandlerError
+	Skipped   []string
+}
+
+func (e *DispatchError) Error() string {
+	msgs := make([]string, 0, len(e.Failures))
+	for _, f := range e.Failures {
+		msgs = append(msgs, f.Error())
+	}
+	return fmt.Sprintf("%s: %d handler(s) failed: %s", e.EventType, len(e.Failures), strings.Join(msgs, "; "))
+}
+
+func (e *DispatchError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Failures))
+	for _, f := range e.Failures {
+		errs = append(errs, f)
+	}
+	return errs
+}
+
+// Failed returns the names of the failed handlers.
+func (e *DispatchError) Failed() []string {
+	names := make([]string, 0, len(e.Failures))
+	for _, f := range e.Failures {
+		names = append(names, f.Handler)
+	}
+	return names
+}
+
+func fanOut(ctx context.Context, policy FanOutPolicy, handlers []namedHandler, event events.Message) *DispatchError {
+	var failures []*HandlerError
+	var skipped []string
+
+	switch policy {
+	case Parallel:
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		for _, h := range handlers {
+			wg.Add(1)
+			go func(h namedHandler) {
+				defer wg.Done()
+				if err := h.handler.Handle(ctx, event); err != nil {
+					mu.Lock()
+					failures = append(failures, &HandlerError{Handler: h.name, Err: err})
+					mu.Unlock()
+				}
+			}(h)
+		}
+		wg.Wait()
+
+	default:
+		for i, h := range handlers {
+			if err := h.handler.Handle(ctx, event); err != nil {
+				failures = append(failures, &HandlerError{Handler: h.name, Err: err})
+				if policy == FailFast {
+					for _, rest := range handlers[i+1:] {
+						skipped = append(skipped, rest.name)
+					}
+					break
+				}
+			}
+		}
+	}
+
+	if len(failures) == 0 {
+		return nil
+	}
+	return &DispatchError{EventType: event.Type, Failures: failures, Skipped: skipped}
+}
@@ -0,0 +1,159 @@
+package dispatcher
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/events"
+)
+
+type countingHandler struct {
+	calls atomic.Int32
+	err   error
+	delay time.Duration
+}
+
+func (h *countingHandler) Handle(ctx context.Context, event events.Message) error {
+	h.calls.Add(1)
+	time.Sleep(h.delay)
+	return h.err
+}
+
+func TestRegisterMultipleHandlers(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	dispatcher := New(logger)
+
+	first, second := &countingHandler{}, &countingHandler{}
+	dispatcher.Register("order.created", first, WithName("billing"))
+	dispatcher.Register("order.created", second, WithName("shipping"))
+
+	if err := dispatcher.Dispatch(context.Background(), events.Message{Type: "order.created"}); err != nil {
+		t.Fatalf("unexpected dispatch error: %v", err)
+	}
+	if first.calls.Load() != 1 || second.calls.Load() != 1 {
+		t.Fatalf("expected both handlers to run once, got %d and %d", first.calls.Load(), second.calls.Load())
+	}
+	if got := dispatcher.HandlerType("order.created"); got != "billing,shipping" {
+		t.Fatalf("expected billing,shipping, got %q", got)
+	}
+}
+
+func TestRegisterSameNameReplaces(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	dispatcher := New(logger)
+
+	old, replacement := &countingHandler{}, &countingHandler{}
+	dispatcher.Register("order.created", old, WithName("billing"))
+	dispatcher.Register("order.created", replacement, WithName("billing"))
+
+	_ = dispatcher.Dispatch(context.Background(), events.Message{Type: "order.created"})
+	if old.calls.Load() != 0 || replacement.calls.Load() != 1 {
+		t.Fatalf("expected only the replacement to run, got %d and %d", old.calls.Load(), replacement.calls.Load())
+	}
+}
+
+func TestRegisterDefaultNamesAreUnique(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	dispatcher := New(logger)
+
+	dispatcher.Register("order.created", &countingHandler{})
+	dispatcher.Register("order.created", &countingHandler{})
+
+	if got := dispatcher.HandlerType("order.created"); got != "*dispatcher.countingHandler,*dispatcher.countingHandler#2" {
+		t.Fatalf("unexpected handler names %q", got)
+	}
+}
+
+func TestFanOutSequentialReportsEachFailure(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	dispatcher := New(logger)
+
+	failure := errors.New("boom")
+	a, b, c := &countingHandler{err: failure}, &countingHandler{}, &countingHandler{err: Permanent(failure)}
+	dispatcher.Register("order.created", a, WithName("a"))
+	dispatcher.Register("order.created", b, WithName("b"))
+	dispatcher.Register("order.created", c, WithName("c"))
+
+	err := dispatcher.Dispatch(context.Background(), events.Message{Type: "order.created"})
+	var dispatchErr *DispatchError
+	if !errors.As(err, &dispatchErr) {
+		t.Fatalf("expected *DispatchError, got %v", err)
+	}
+	if failed := dispatchErr.Failed(); len(failed) != 2 || failed[0] != "a" || failed[1] != "c" {
+		t.Fatalf("expected a and c to fail, got %v", failed)
+	}
+	if b.calls.Load() != 1 {
+		t.Fatal("expected sequential policy to keep running after a failure")
+	}
+	if !errors.Is(err, failure) || !IsPermanent(err) {
+		t.Fatal("expected handler errors to be reachable through the dispatch error")
+	}
+}
+
+func TestFanOutFailFast(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	dispatcher := New(logger)
+	dispatcher.SetFanOutFor("order.created", FailFast)
+
+	a, b, c := &countingHandler{}, &countingHandler{err: errors.New("boom")}, &countingHandler{}
+	dispatcher.Register("order.created", a, WithName("a"))
+	dispatcher.Register("order.created", b, WithName("b"))
+	dispatcher.Register("order.created", c, WithName("c"))
+
+	err := dispatcher.Dispatch(context.Background(), events.Message{Type: "order.created"})
+	var dispatchErr *DispatchError
+	if !errors.As(err, &dispatchErr) {
+		t.Fatalf("expected *DispatchError, got %v", err)
+	}
+	if c.calls.Load() != 0 {
+		t.Fatal("expected fail-fast policy to stop after the first failure")
+	}
+	if len(dispatchErr.Skipped) != 1 || dispatchErr.Skipped[0] != "c" {
+		t.Fatalf("expected c to be skipped, got %v", dispatchErr.Skipped)
+	}
+}
+
+func TestFanOutParallel(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	dispatcher := New(logger)
+	dispatcher.SetFanOut(Parallel)
+
+	for _, name := range []string{"a", "b", "c"} {
+		dispatcher.Register("order.created", &countingHandler{delay: 50 * time.Millisecond}, WithName(name))
+	}
+
+	start := time.Now()
+	if err := dispatcher.Dispatch(context.Background(), events.Message{Type: "order.created"}); err != nil {
+		t.Fatalf("unexpected dispatch error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 140*time.Millisecond {
+		t.Fatalf("expected handlers to run concurrently, took %s", elapsed)
+	}
+}
+
+func TestDispatchHandlersRunsOnlyNamedHandlers(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	dispatcher := New(logger)
+
+	a, b := &countingHandler{}, &countingHandler{}
+	dispatcher.Register("order.created", a, WithName("a"))
+	dispatcher.Register("order.created", b, WithName("b"))
+
+	if err := dispatcher.DispatchHandlers(context.Background(), events.Message{Type: "order.created"}, []string{"b"}); err != nil {
+		t.Fatalf("unexpected dispatch error: %v", err)
+	}
+	if a.calls.Load() != 0 || b.calls.Load() != 1 {
+		t.Fatalf("expected only b to run, got %d and %d", a.calls.Load(), b.calls.Load())
+	}
+}
+
+func TestFanOutPolicyString(t *testing.T) {
+	if Sequential.String() != "sequential" || Parallel.String() != "parallel" || FailFast.String() != "fail-fast" {
+		t.Fatal("unexpected policy names")
+	}
+}
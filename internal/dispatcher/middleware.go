@@ -0,0 +1,127 @@
+package dispatcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"time"
+
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/events"
+)
+
+var (
+	ErrHandlerPanic   = errors.New("handler panicked")
+	ErrHandlerTimeout = errors.New("handler timed out")
+)
+
+// Middleware wraps a Handler to add behaviour around it.
+type Middleware func(Handler) Handler
+
+// chain wraps h so that mw[0] runs outermost.
+func chain(h Handler, mw []Middleware) Handler {
+	for i := len(mw) - 1; i >= 0; i-- {
+		h = mw[i](h)
+	}
+	return h
+}
+
+// panicError carries a panic recovered on another goroutine, such as the
+// one Timeout runs the handler on, back to Recovery with its stack.
+type panicError struct {
+	value any
+	stack []byte
+}
+
+func (e *panicError) Error() string { return fmt.Sprintf("%s: %v", ErrHandlerPanic, e.value) }
+
+func (e *panicError) Unwrap() error { return ErrHandlerPanic }
+
+// Recovery turns a handler panic into an error wrapping ErrHandlerPanic. It
+// also logs panics that Timeout recovered on the handler goroutine.
+func Recovery(logger *slog.Logger) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(ctx context.Context, event events.Message) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					err = &panicError{value: r, stack: debug.Stack()}
+				}
+				var perr *panicError
+				if errors.As(err, &perr) {
+					logger.Error("handler panicked", "event_id", event.ID, "event_type", event.Type, "panic", event.Redact(perr.value), "stack", string(perr.stack))
+				}
+			}()
+			return next.Handle(ctx, event)
+		})
+	}
+}
+
+// Timeout cancels the handler context after d. If the handler does not
+// return by then, Timeout stops waiting for it and returns an error wrapping
+// ErrHandlerTimeout. A panic in the handler is recovered on its goroutine and
+// returned as an error wrapping ErrHandlerPanic.
+func Timeout(d time.Duration) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(ctx context.Context, event events.Message) error {
+			ctx, cancel := context.WithTimeout(ctx, d)
+			defer cancel()
+
+			done := make(chan error, 1)
+			go func() {
+				defer func() {
+					if r := recover(); r != nil {
+						done <- &panicError{value: r, stack: debug.Stack()}
+					}
+				}()
+				done <- next.Handle(ctx, event)
+			}()
+
+			select {
+			case err := <-done:
+				return err
+			case <-ctx.Done():
+				return fmt.Errorf("%w after %s: %w", ErrHandlerTimeout, d, ctx.Err())
+			}
+		})
+	}
+}
+
+// AccessLog logs every handled event with its duration and outcome, and its
+// correlation ID when it has one.
+func AccessLog(logger *slog.Logger) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(ctx context.Context, event events.Message) error {
+			start := time.Now()
+			err := next.Handle(ctx, event)
+			attrs := []any{
+				"event_id", event.ID,
+				"event_type", event.Type,
+				"source", event.Source,
+				"duration", time.Since(start),
+			}
+			if id := event.CorrelationID(); id != "" {
+				attrs = append(attrs, "correlation_id", id)
+			}
+			if err != nil {
+				logger.Warn("event handled", append(attrs, "error", event.Redact(err))...)
+				return err
+			}
+			logger.Info("event handled", attrs...)
+			return nil
+		})
+	}
+}
+
+// Latency reports how long each handler call took to observe, with the name
+// of the handler, so the handlers of one event type can be told apart.
+func Latency(observe func(eventType, handler string, d time.Duration, err error)) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(ctx context.Context, event events.Message) error {
+			start := time.Now()
+			err := next.Handle(ctx, event)
+			observe(event.Type, HandlerName(ctx), time.Since(start), err)
+			return err
+		})
+	}
+}
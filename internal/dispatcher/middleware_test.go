@@ -0,0 +1,228 @@
+package dispatcher
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/events"
+)
+
+func recordingMiddleware(name string, calls *[]string) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(ctx context.Context, event events.Message) error {
+			*calls = append(*calls, name)
+			return next.Handle(ctx, event)
+		})
+	}
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	dispatcher := New(logger)
+
+	var calls []string
+	dispatcher.Register("test_event", HandlerFunc(func(ctx context.Context, event events.Message) error {
+		calls = append(calls, "handler")
+		return nil
+	}))
+	dispatcher.UseFor("test_event", recordingMiddleware("typed", &calls))
+	dispatcher.Use(recordingMiddleware("first", &calls), recordingMiddleware("second", &calls))
+
+	if err := dispatcher.Dispatch(context.Background(), events.Message{Type: "test_event"}); err != nil {
+		t.Fatalf("unexpected dispatch error: %v", err)
+	}
+
+	if got := strings.Join(calls, ","); got != "first,second,typed,handler" {
+		t.Fatalf("unexpected call order %s", got)
+	}
+}
+
+func TestMiddlewareForOtherTypeNotApplied(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	dispatcher := New(logger)
+
+	var calls []string
+	dispatcher.Register("test_event", &mockHandler{})
+	dispatcher.UseFor("other_event", recordingMiddleware("typed", &calls))
+
+	if err := dispatcher.Dispatch(context.Background(), events.Message{Type: "test_event"}); err != nil {
+		t.Fatalf("unexpected dispatch error: %v", err)
+	}
+	if len(calls) != 0 {
+		t.Fatalf("expected no middleware calls, got %v", calls)
+	}
+}
+
+func TestMiddlewareSetsHeaders(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	dispatcher := New(logger)
+	dispatcher.SetFanOut(Parallel)
+
+	tenant := func(next Handler) Handler {
+		return HandlerFunc(func(ctx context.Context, event events.Message) error {
+			event.SetTenantID("acme")
+			return next.Handle(ctx, event)
+		})
+	}
+	dispatcher.UseFor("test_event", tenant)
+
+	seen := make(chan string, 2)
+	for _, name := range []string{"a", "b"} {
+		dispatcher.Register("test_event", HandlerFunc(func(ctx context.Context, event events.Message) error {
+			event.SetHeader("handled-by", name)
+			seen <- event.TenantID() + "/" + event.CorrelationID()
+			return nil
+		}), WithName(name))
+	}
+
+	event := events.Message{Type: "test_event", Headers: map[string]string{events.HeaderCorrelationID: "c-1"}}
+	if err := dispatcher.Dispatch(context.Background(), event); err != nil {
+		t.Fatalf("unexpected dispatch error: %v", err)
+	}
+	for range 2 {
+		if got := <-seen; got != "acme/c-1" {
+			t.Fatalf("expected handler to see tenant and correlation IDs, got %s", got)
+		}
+	}
+	if len(event.Headers) != 1 {
+		t.Fatalf("expected the dispatched event to be unchanged, got %v", event.Headers)
+	}
+}
+
+func TestRecovery(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	dispatcher := New(logger)
+	dispatcher.Use(Recovery(logger))
+	dispatcher.Register("test_event", HandlerFunc(func(ctx context.Context, event events.Message) error {
+		panic("boom")
+	}))
+
+	err := dispatcher.Dispatch(context.Background(), events.Message{Type: "test_event"})
+	if !errors.Is(err, ErrHandlerPanic) {
+		t.Fatalf("expected ErrHandlerPanic, got %v", err)
+	}
+}
+
+func TestRecoveryWithTimeout(t *testing.T) {
+	var buf strings.Builder
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	dispatcher := New(logger)
+	dispatcher.Use(Recovery(logger), Timeout(time.Second))
+	dispatcher.Register("test_event", HandlerFunc(func(ctx context.Context, event events.Message) error {
+		panic("boom")
+	}))
+
+	err := dispatcher.Dispatch(context.Background(), events.Message{ID: "1", Type: "test_event"})
+	if !errors.Is(err, ErrHandlerPanic) {
+		t.Fatalf("expected ErrHandlerPanic, got %v", err)
+	}
+	if out := buf.String(); !strings.Contains(out, "panic=boom") || !strings.Contains(out, "stack=") {
+		t.Errorf("expected the panic to be logged with its stack, got %s", out)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	dispatcher := New(logger)
+	dispatcher.UseFor("slow_event", Timeout(20*time.Millisecond))
+	dispatcher.Register("slow_event", HandlerFunc(func(ctx context.Context, event events.Message) error {
+		time.Sleep(time.Second)
+		return nil
+	}))
+	dispatcher.Register("fast_event", HandlerFunc(func(ctx context.Context, event events.Message) error {
+		return nil
+	}))
+
+	start := time.Now()
+	err := dispatcher.Dispatch(context.Background(), events.Message{Type: "slow_event"})
+	if !errors.Is(err, ErrHandlerTimeout) {
+		t.Fatalf("expected ErrHandlerTimeout, got %v", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected error to wrap context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("expected dispatch to return after the timeout, took %s", elapsed)
+	}
+
+	if err := dispatcher.Dispatch(context.Background(), events.Message{Type: "fast_event"}); err != nil {
+		t.Fatalf("unexpected error for fast handler: %v", err)
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	var buf strings.Builder
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	dispatcher := New(slog.New(slog.NewTextHandler(os.Stdout, nil)))
+	dispatcher.Use(AccessLog(logger))
+	dispatcher.Register("test_event", &mockHandler{})
+	dispatcher.Register("error_event", &mockHandlerWithError{})
+
+	_ = dispatcher.Dispatch(context.Background(), events.Message{ID: "1", Type: "test_event", Headers: map[string]string{events.HeaderCorrelationID: "c-1"}})
+	_ = dispatcher.Dispatch(context.Background(), events.Message{ID: "2", Type: "error_event"})
+
+	out := buf.String()
+	if !strings.Contains(out, "level=INFO") || !strings.Contains(out, "event_id=1") || !strings.Contains(out, "correlation_id=c-1") {
+		t.Errorf("expected info entry for event 1, got %s", out)
+	}
+	if !strings.Contains(out, "level=WARN") || !strings.Contains(out, "event_id=2") || !strings.Contains(out, "error=") {
+		t.Errorf("expected warn entry with error for event 2, got %s", out)
+	}
+}
+
+func TestEncryptedEventErrorsAreRedacted(t *testing.T) {
+	var buf strings.Builder
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	dispatcher := New(logger)
+	dispatcher.Use(Recovery(logger), AccessLog(logger))
+	dispatcher.Register("error_event", HandlerFunc(func(ctx context.Context, event events.Message) error {
+		return errors.New("card 4111-1111 declined")
+	}))
+	dispatcher.Register("panic_event", HandlerFunc(func(ctx context.Context, event events.Message) error {
+		panic("card 4111-1111")
+	}))
+
+	_ = dispatcher.Dispatch(context.Background(), events.Message{ID: "1", Type: "error_event", Encrypted: true})
+	_ = dispatcher.Dispatch(context.Background(), events.Message{ID: "2", Type: "panic_event", Encrypted: true})
+
+	out := buf.String()
+	if strings.Contains(out, "4111") {
+		t.Errorf("expected encrypted event data to be redacted, got %s", out)
+	}
+	if !strings.Contains(out, "error="+events.Redacted) || !strings.Contains(out, "panic="+events.Redacted) {
+		t.Errorf("expected redacted error and panic, got %s", out)
+	}
+}
+
+func TestLatency(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	dispatcher := New(logger)
+
+	var observedType, observedHandler string
+	var observed time.Duration
+	var observedErr error
+	dispatcher.Use(Latency(func(eventType, handler string, d time.Duration, err error) {
+		observedType, observedHandler, observed, observedErr = eventType, handler, d, err
+	}))
+	dispatcher.Register("error_event", HandlerFunc(func(ctx context.Context, event events.Message) error {
+		time.Sleep(10 * time.Millisecond)
+		return os.ErrInvalid
+	}), WithName("slow"))
+
+	_ = dispatcher.Dispatch(context.Background(), events.Message{Type: "error_event"})
+
+	if observedType != "error_event" || observedHandler != "slow" {
+		t.Errorf("expected event type error_event and handler slow, got %q and %q", observedType, observedHandler)
+	}
+	if observed < 10*time.Millisecond {
+		t.Errorf("expected latency of at least 10ms, got %s", observed)
+	}
+	if !errors.Is(observedErr, os.ErrInvalid) {
+		t.Errorf("expected handler error to be observed, got %v", observedErr)
+	}
+}
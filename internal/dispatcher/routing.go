@@ -0,0 +1,110 @@
+package dispatcher
+
+import (
+	"strings"
+)
+
+// Event types are dot-separated. Handlers may be registered for patterns in
+// which "*" matches exactly one segment and "#" matches zero or more, so
+// "order.*" matches "order.created" and "order.#" also matches "order" and
+// "order.item.added".
+
+// maxCachedRoutes bounds the route cache, since event types come off the wire.
+const maxCachedRoutes = 10000
+
+// FallbackName is the handler name reported for the fallback handler.
+const FallbackName = "fallback"
+
+type route struct {
+	key string
+	ok  bool
+}
+
+func isPattern(key string) bool {
+	return strings.ContainsAny(key, "*#")
+}
+
+// match reports whether the segments of an event type match pattern.
+func match(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	switch pattern[0] {
+	case "#":
+		for i := 0; i <= len(segments); i++ {
+			if match(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	case "*":
+		return len(segments) > 0 && match(pattern[1:], segments[1:])
+	default:
+		return len(segments) > 0 && pattern[0] == segments[0] && match(pattern[1:], segments[1:])
+	}
+}
+
+func segmentRank(segment string) int {
+	switch segment {
+	case "#":
+		return 2
+	case "*":
+		return 1
+	default:
+		return 0
+	}
+}
+
+// moreSpecific reports whether pattern a should win over pattern b. Segments
+// are compared left to right, a literal beating "*" and "*" beating "#"; the
+// longer pattern wins a tie, then the lexically smaller one.
+func moreSpecific(a, b string) bool {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		ra, rb := segmentRank(as[i]), segmentRank(bs[i])
+		if ra != rb {
+			return ra < rb
+		}
+	}
+	if len(as) != len(bs) {
+		return len(as) > len(bs)
+	}
+	return a < b
+}
+
+// resolve finds the registration key for eventType: the exact key if one is
+// registered, otherwise the most specific matching pattern. d.mu must be held.
+func (d *Dispatcher) resolve(eventType string) (string, bool) {
+	if _, ok := d.handlers[eventType]; ok {
+		return eventType, true
+	}
+
+	d.routeMu.Lock()
+	defer d.routeMu.Unlock()
+	if r, ok := d.routes[eventType]; ok {
+		return r.key, r.ok
+	}
+
+	var best route
+	segments := strings.Split(eventType, ".")
+	for key := range d.handlers {
+		if !isPattern(key) || !match(strings.Split(key, "."), segments) {
+			continue
+		}
+		if !best.ok || moreSpecific(key, best.key) {
+			best = route{key: key, ok: true}
+		}
+	}
+
+	if len(d.routes) >= maxCachedRoutes {
+		clear(d.routes)
+	}
+	d.routes[eventType] = best
+	return best.key, best.ok
+}
+
+func (d *Dispatcher) clearRoutes() {
+	d.routeMu.Lock()
+	defer d.routeMu.Unlock()
+	clear(d.routes)
+}
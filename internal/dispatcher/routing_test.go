@@ -0,0 +1,121 @@
+package dispatcher
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/events"
+)
+
+func TestMatch(t *testing.T) {
+	cases := []struct {
+		pattern   string
+		eventType string
+		want      bool
+	}{
+		{"order.*", "order.created", true},
+		{"order.*", "order", false},
+		{"order.*", "order.item.added", false},
+		{"order.#", "order", true},
+		{"order.#", "order.created", true},
+		{"order.#", "order.item.added", true},
+		{"*.created", "order.created", true},
+		{"*.created", "order.updated", false},
+		{"#.created", "shop.order.created", true},
+		{"order.#.added", "order.added", true},
+		{"order.#.added", "order.item.line.added", true},
+		{"#", "anything.at.all", true},
+	}
+	for _, c := range cases {
+		got := match(strings.Split(c.pattern, "."), strings.Split(c.eventType, "."))
+		if got != c.want {
+			t.Errorf("match(%q, %q) = %v, want %v", c.pattern, c.eventType, got, c.want)
+		}
+	}
+}
+
+func TestMostSpecificPatternWins(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	dispatcher := New(logger)
+
+	var got string
+	for _, key := range []string{"#", "order.#", "*.created", "order.*", "order.created"} {
+		dispatcher.Register(key, HandlerFunc(func(ctx context.Context, event events.Message) error {
+			got = key
+			return nil
+		}))
+	}
+
+	cases := map[string]string{
+		"order.created":   "order.created",
+		"order.updated":   "order.*",
+		"invoice.created": "*.created",
+		"order.item.add":  "order.#",
+		"order":           "order.#",
+		"user.signed_up":  "#",
+	}
+	for eventType, want := range cases {
+		got = ""
+		if err := dispatcher.Dispatch(context.Background(), events.Message{Type: eventType}); err != nil {
+			t.Fatalf("unexpected dispatch error: %v", err)
+		}
+		if got != want {
+			t.Errorf("%s: expected %s to handle it, got %s", eventType, want, got)
+		}
+	}
+}
+
+func TestRouteCacheInvalidatedOnRegister(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	dispatcher := New(logger)
+
+	general, specific := &countingHandler{}, &countingHandler{}
+	dispatcher.Register("order.#", general)
+	_ = dispatcher.Dispatch(context.Background(), events.Message{Type: "order.created"})
+
+	dispatcher.Register("order.*", specific)
+	_ = dispatcher.Dispatch(context.Background(), events.Message{Type: "order.created"})
+
+	if general.calls.Load() != 1 || specific.calls.Load() != 1 {
+		t.Fatalf("expected each handler to run once, got %d and %d", general.calls.Load(), specific.calls.Load())
+	}
+	if len(dispatcher.routes) != 1 {
+		t.Fatalf("expected one cached route, got %d", len(dispatcher.routes))
+	}
+}
+
+func TestFallbackHandler(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	dispatcher := New(logger)
+
+	known, fallback := &countingHandler{}, &countingHandler{}
+	dispatcher.Register("order.*", known)
+	dispatcher.SetFallback(fallback)
+
+	_ = dispatcher.Dispatch(context.Background(), events.Message{Type: "order.created"})
+	_ = dispatcher.Dispatch(context.Background(), events.Message{Type: "user.created"})
+
+	if known.calls.Load() != 1 || fallback.calls.Load() != 1 {
+		t.Fatalf("expected each handler to run once, got %d and %d", known.calls.Load(), fallback.calls.Load())
+	}
+	if got := dispatcher.HandlerType("user.created"); got != FallbackName {
+		t.Fatalf("expected %s, got %q", FallbackName, got)
+	}
+}
+
+func TestMiddlewareForPattern(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	dispatcher := New(logger)
+
+	var calls []string
+	dispatcher.Register("order.*", &mockHandler{})
+	dispatcher.UseFor("order.*", recordingMiddleware("orders", &calls))
+
+	_ = dispatcher.Dispatch(context.Background(), events.Message{Type: "order.created"})
+	if len(calls) != 1 {
+		t.Fatalf("expected pattern middleware to run, got %v", calls)
+	}
+}
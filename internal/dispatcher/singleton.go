@@ -0,0 +1,118 @@
+package dispatcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/events"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Locker claims an event for one node of the fleet.
+type Locker interface {
+	// Acquire claims key for ttl. ok is false when another node holds it or
+	// has completed it.
+	Acquire(ctx context.Context, key string, ttl time.Duration) (token string, ok bool, err error)
+	// Complete keeps a claim held with token for ttl, so nodes receiving the
+	// event late skip it too.
+	Complete(ctx context.Context, key, token string, ttl time.Duration) error
+	// Release gives up a claim held with token.
+	Release(ctx context.Context, key, token string) error
+}
+
+type singleton struct {
+	locker Locker
+	ttl    time.Duration
+}
+
+// Singleton runs the handler on only one node of a broadcast fleet. Every
+// node tries to claim the event under its ID and the handler name; the
+// winner runs the handler and the others skip it. A claim expires after
+// ttl, so an event whose winner crashed can be claimed again when it is
+// redelivered; ttl should exceed the handler's running time. A failed
+// handler releases its claim so retries can claim it again. The claim is
+// settled inside the middleware chain, once the handler has returned, so a
+// handler Timeout gave up on keeps it while still running.
+func Singleton(locker Locker, ttl time.Duration) RegisterOption {
+	return func(h *namedHandler) { h.singleton = &singleton{locker: locker, ttl: ttl} }
+}
+
+// errClaimAbandoned is returned to middleware that runs the handler after the
+// guard has already released the claim, such as Timeout's goroutine starting
+// late.
+var errClaimAbandoned = errors.New("singleton claim already released")
+
+// guard runs handler, wrapped in middleware by wrap, only if this node claims
+// the event. The claim is settled when handler itself returns, not when the
+// middleware does: a handler Timeout stopped waiting for still holds the
+// claim until it finishes, so no other node runs it meanwhile.
+func (s *singleton) guard(d *Dispatcher, name string, handler Handler, wrap func(Handler) Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, event events.Message) error {
+		if event.ID == "" {
+			d.logger.Warn("singleton handler running without a claim, event has no ID", "event_type", event.Type, "handler", name)
+			return wrap(handler).Handle(ctx, event)
+		}
+		key := name + ":" + event.ID
+		token, ok, err := s.locker.Acquire(ctx, key, s.ttl)
+		if err != nil {
+			return fmt.Errorf("claim event for singleton handler: %w", err)
+		}
+		if !ok {
+			trace.SpanFromContext(ctx).AddEvent("claimed by another node")
+			d.logger.Debug("singleton handler skipped, event claimed by another node", "event_id", event.ID, "handler", name)
+			return nil
+		}
+
+		// the handler context may have timed out, but the claim must still be
+		// settled
+		settleCtx := context.WithoutCancel(ctx)
+		var once sync.Once
+		settle := func(err error) {
+			once.Do(func() {
+				if err != nil {
+					if err := s.locker.Release(settleCtx, key, token); err != nil {
+						d.logger.Error("failed to release singleton claim", "event_id", event.ID, "handler", name, "error", err)
+					}
+					return
+				}
+				if err := s.locker.Complete(settleCtx, key, token, s.ttl); err != nil {
+					d.logger.Error("failed to complete singleton claim", "event_id", event.ID, "handler", name, "error", err)
+				}
+			})
+		}
+
+		var mu sync.Mutex
+		started, abandoned := false, false
+		inner := HandlerFunc(func(ctx context.Context, event events.Message) (err error) {
+			mu.Lock()
+			if abandoned {
+				mu.Unlock()
+				return errClaimAbandoned
+			}
+			started = true
+			mu.Unlock()
+			defer func() {
+				if r := recover(); r != nil {
+					settle(ErrHandlerPanic)
+					panic(r)
+				}
+				settle(err)
+			}()
+			return handler.Handle(ctx, event)
+		})
+
+		err = wrap(inner).Handle(ctx, event)
+		mu.Lock()
+		abandoned = !started
+		mu.Unlock()
+		if abandoned {
+			// middleware returned without running the handler
+			settle(errClaimAbandoned)
+		}
+		return err
+	})
+}
@@ -0,0 +1,151 @@
+package dispatcher
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/events"
+)
+
+// memoryLocker is a Locker shared by several dispatchers standing in for the
+// nodes of a fleet. Claims never expire.
+type memoryLocker struct {
+	mu     sync.Mutex
+	claims map[string]string
+	next   int
+}
+
+func (l *memoryLocker) Acquire(ctx context.Context, key string, ttl time.Duration) (string, bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.claims[key]; ok {
+		return "", false, nil
+	}
+	l.next++
+	token := strconv.Itoa(l.next)
+	l.claims[key] = token
+	return token, true, nil
+}
+
+func (l *memoryLocker) Complete(ctx context.Context, key, token string, ttl time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.claims[key] == token {
+		l.claims[key] = "done"
+	}
+	return nil
+}
+
+func (l *memoryLocker) Release(ctx context.Context, key, token string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.claims[key] == token {
+		delete(l.claims, key)
+	}
+	return nil
+}
+
+func TestSingletonRunsOnOneNode(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+	locker := &memoryLocker{claims: make(map[string]string)}
+
+	var emails, audits atomic.Int32
+	var nodes []*Dispatcher
+	for range 3 {
+		d := New(logger)
+		d.Register("user.signed_up", HandlerFunc(func(ctx context.Context, event events.Message) error {
+			emails.Add(1)
+			return nil
+		}), WithName("welcome-email"), Singleton(locker, time.Minute))
+		d.Register("user.signed_up", HandlerFunc(func(ctx context.Context, event events.Message) error {
+			audits.Add(1)
+			return nil
+		}), WithName("audit"))
+		nodes = append(nodes, d)
+	}
+
+	for _, d := range nodes {
+		if err := d.Dispatch(context.Background(), events.Message{ID: "1", Type: "user.signed_up"}); err != nil {
+			t.Fatalf("unexpected dispatch error: %v", err)
+		}
+	}
+	if emails.Load() != 1 || audits.Load() != 3 {
+		t.Fatalf("expected 1 email and 3 audits, got %d and %d", emails.Load(), audits.Load())
+	}
+	if locker.claims["welcome-email:1"] != "done" {
+		t.Fatalf("expected the claim to be completed, got %q", locker.claims["welcome-email:1"])
+	}
+}
+
+func TestSingletonReleasesClaimOnFailure(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+	locker := &memoryLocker{claims: make(map[string]string)}
+
+	var calls atomic.Int32
+	d := New(logger)
+	d.Register("user.signed_up", HandlerFunc(func(ctx context.Context, event events.Message) error {
+		if calls.Add(1) == 1 {
+			return errors.New("smtp unavailable")
+		}
+		return nil
+	}), Singleton(locker, time.Minute))
+
+	event := events.Message{ID: "1", Type: "user.signed_up"}
+	if err := d.Dispatch(context.Background(), event); err == nil {
+		t.Fatal("expected the first attempt to fail")
+	}
+	if err := d.Dispatch(context.Background(), event); err != nil {
+		t.Fatalf("expected the retry to claim the event again, got %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("expected 2 calls, got %d", calls.Load())
+	}
+}
+
+func TestSingletonKeepsClaimUntilTimedOutHandlerReturns(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+	locker := &memoryLocker{claims: make(map[string]string)}
+
+	finish := make(chan struct{})
+	returned := make(chan struct{})
+	d := New(logger)
+	d.Use(Recovery(logger), Timeout(20*time.Millisecond))
+	d.Register("report.generate", HandlerFunc(func(ctx context.Context, event events.Message) error {
+		defer close(returned)
+		<-finish
+		return nil
+	}), WithName("report"), Singleton(locker, time.Minute))
+
+	event := events.Message{ID: "1", Type: "report.generate"}
+	if err := d.Dispatch(context.Background(), event); !errors.Is(err, ErrHandlerTimeout) {
+		t.Fatalf("expected a timeout, got %v", err)
+	}
+	locker.mu.Lock()
+	claim := locker.claims["report:1"]
+	locker.mu.Unlock()
+	if claim == "" || claim == "done" {
+		t.Fatalf("expected the claim to be held while the handler runs, got %q", claim)
+	}
+
+	close(finish)
+	<-returned
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		locker.mu.Lock()
+		claim = locker.claims["report:1"]
+		locker.mu.Unlock()
+		if claim == "done" || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if claim != "done" {
+		t.Fatalf("expected the claim to be completed once the handler returned, got %q", claim)
+	}
+}
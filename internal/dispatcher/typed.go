@@ -0,0 +1,30 @@
+package dispatcher
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/events"
+)
+
+// RegisterTyped registers handle for eventType with its payload decoded into
+// T. A payload that does not decode fails permanently, so it is not retried.
+//
+//	dispatcher.RegisterTyped(d, "order.created", func(ctx context.Context, env events.Envelope[OrderCreated]) error {
+//		return ship(env.Payload.OrderID)
+//	})
+func RegisterTyped[T any](d *Dispatcher, eventType string, handle func(ctx context.Context, env events.Envelope[T]) error, opts ...RegisterOption) {
+	d.Register(eventType, typedHandler[T]{handle: handle}, opts...)
+}
+
+type typedHandler[T any] struct {
+	handle func(ctx context.Context, env events.Envelope[T]) error
+}
+
+func (h typedHandler[T]) Handle(ctx context.Context, event events.Message) error {
+	env, err := events.Open[T](event)
+	if err != nil {
+		return Permanent(fmt.Errorf("decode %s payload into %T: %w", event.Type, env.Payload, err))
+	}
+	return h.handle(ctx, env)
+}
@@ -0,0 +1,81 @@
+package dispatcher
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/events"
+)
+
+type orderCreated struct {
+	OrderID string  `json:"order_id"`
+	Total   float64 `json:"total"`
+}
+
+func TestRegisterTyped(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	dispatcher := New(logger)
+
+	var got events.Envelope[orderCreated]
+	RegisterTyped(dispatcher, "order.created", func(ctx context.Context, env events.Envelope[orderCreated]) error {
+		got = env
+		return nil
+	})
+
+	event := events.Message{
+		ID:      "1",
+		Type:    "order.created",
+		Payload: map[string]any{"order_id": "o-1", "total": 9.5},
+		Headers: map[string]string{events.HeaderCorrelationID: "c-1"},
+	}
+	if err := dispatcher.Dispatch(context.Background(), event); err != nil {
+		t.Fatalf("unexpected dispatch error: %v", err)
+	}
+	if got.Payload.OrderID != "o-1" || got.Payload.Total != 9.5 {
+		t.Fatalf("expected decoded payload, got %+v", got.Payload)
+	}
+	if got.ID != "1" || got.CorrelationID() != "c-1" {
+		t.Fatalf("expected envelope fields to be kept, got %+v", got.Message)
+	}
+	if name := dispatcher.HandlerType("order.created"); !strings.Contains(name, "typedHandler") || !strings.Contains(name, "orderCreated") {
+		t.Fatalf("expected handler to be named after its payload type, got %s", name)
+	}
+}
+
+func TestRegisterTypedDecodeFailureIsPermanent(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	dispatcher := New(logger)
+
+	called := false
+	RegisterTyped(dispatcher, "order.created", func(ctx context.Context, env events.Envelope[orderCreated]) error {
+		called = true
+		return nil
+	})
+
+	err := dispatcher.Dispatch(context.Background(), events.Message{Type: "order.created", Payload: map[string]any{"total": "lots"}})
+	if !IsPermanent(err) {
+		t.Fatalf("expected a permanent error, got %v", err)
+	}
+	if called {
+		t.Fatal("expected handler not to be called")
+	}
+}
+
+func TestRegisterTypedHandlerError(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	dispatcher := New(logger)
+
+	handlerErr := errors.New("out of stock")
+	RegisterTyped(dispatcher, "order.created", func(ctx context.Context, env events.Envelope[orderCreated]) error {
+		return handlerErr
+	})
+
+	err := dispatcher.Dispatch(context.Background(), events.Message{Type: "order.created", Payload: map[string]any{}})
+	if !errors.Is(err, handlerErr) || IsPermanent(err) {
+		t.Fatalf("expected the handler error as is, got %v", err)
+	}
+}
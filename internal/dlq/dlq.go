@@ -0,0 +1,50 @@
+package dlq
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/events"
+)
+
+var (
+	ErrNotFound = errors.New("dead-letter record not found")
+	// ErrFenced is returned by a fenced store once the lease guarding its
+	// writes has been lost.
+	ErrFenced = errors.New("lease lost, write fenced off")
+)
+
+// Record is an event that could not be processed, along with why. Channel is
+// the channel, or stream, the event was received on, which the message does
+// not keep when it is encoded.
+type Record struct {
+	ID          string         `json:"id"`
+	Message     events.Message `json:"message"`
+	Channel     string         `json:"channel,omitempty"`
+	Error       string         `json:"error"`
+	Attempts    int            `json:"attempts"`
+	HandlerType string         `json:"handler_type"`
+	FailedAt    time.Time      `json:"failed_at"`
+}
+
+// Sink receives records for events that exhausted their retries.
+type Sink interface {
+	Put(ctx context.Context, record Record) error
+}
+
+// Store is a Sink whose records can be inspected and managed. Records are
+// listed oldest first and identified by the ID the store assigns on Put.
+type Store interface {
+	Sink
+	List(ctx context.Context, limit int64) ([]Record, error)
+	// ScanBefore calls fn, oldest first, for every record stored before
+	// cutoff, and so failed before it too, without reading newer records. A
+	// record that cannot be decoded is passed with its ID and the error
+	// instead of ending the scan. The scan stops at the first error fn
+	// returns.
+	ScanBefore(ctx context.Context, cutoff time.Time, fn func(id string, record Record, err error) error) error
+	Get(ctx context.Context, id string) (Record, error)
+	Delete(ctx context.Context, id string) error
+	Purge(ctx context.Context) (int64, error)
+}
@@ -0,0 +1,193 @@
+package dlq
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/codec"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/events"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/keyring"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func testStore(t *testing.T, store Store) {
+	ctx := context.Background()
+	failedAt := time.Date(2026, 2, 22, 10, 0, 0, 0, time.UTC)
+
+	for _, id := range []string{"a", "b", "c"} {
+		err := store.Put(ctx, Record{
+			Message:     events.Message{ID: id, Type: "demo.message"},
+			Error:       "simulated failure",
+			Attempts:    4,
+			HandlerType: "*handlers.DemoMessageHandler",
+			FailedAt:    failedAt,
+		})
+		if err != nil {
+			t.Fatalf("unexpected put error: %v", err)
+		}
+	}
+
+	records, err := store.List(ctx, 0)
+	if err != nil {
+		t.Fatalf("unexpected list error: %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("expected 3 records, got %d", len(records))
+	}
+	if records[0].Message.ID != "a" || records[2].Message.ID != "c" {
+		t.Fatalf("expected records oldest first, got %+v", records)
+	}
+
+	limited, err := store.List(ctx, 2)
+	if err != nil {
+		t.Fatalf("unexpected list error: %v", err)
+	}
+	if len(limited) != 2 {
+		t.Fatalf("expected 2 records with limit, got %d", len(limited))
+	}
+
+	got, err := store.Get(ctx, records[1].ID)
+	if err != nil {
+		t.Fatalf("unexpected get error: %v", err)
+	}
+	if got.Message.ID != "b" || got.Error != "simulated failure" || got.Attempts != 4 ||
+		got.HandlerType != "*handlers.DemoMessageHandler" || !got.FailedAt.Equal(failedAt) {
+		t.Fatalf("record did not round-trip: %+v", got)
+	}
+
+	if err := store.Delete(ctx, records[1].ID); err != nil {
+		t.Fatalf("unexpected delete error: %v", err)
+	}
+	if _, err := store.Get(ctx, records[1].ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound after delete, got %v", err)
+	}
+	if err := store.Delete(ctx, records[1].ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound deleting twice, got %v", err)
+	}
+
+	n, err := store.Purge(ctx)
+	if err != nil {
+		t.Fatalf("unexpected purge error: %v", err)
+	}
+	if n != 2 {
+		t.Fatalf("expected 2 purged records, got %d", n)
+	}
+	if records, _ := store.List(ctx, 0); len(records) != 0 {
+		t.Fatalf("expected empty store after purge, got %d records", len(records))
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore())
+}
+
+func TestRedisStore(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	testStore(t, NewRedisStore(client, "events.dlq"))
+}
+
+func TestRedisStoreScanBefore(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	ctx := context.Background()
+	store := NewRedisStore(client, "events.dlq")
+
+	old := time.Now().Add(-time.Hour)
+	mr.SetTime(old)
+	for range scanPageSize + 10 {
+		if err := store.Put(ctx, Record{Error: "boom"}); err != nil {
+			t.Fatalf("unexpected put error: %v", err)
+		}
+	}
+	mr.SetTime(time.Now())
+	if err := store.Put(ctx, Record{Error: "recent"}); err != nil {
+		t.Fatalf("unexpected put error: %v", err)
+	}
+
+	seen := make(map[string]bool)
+	err := store.ScanBefore(ctx, time.Now().Add(-time.Minute), func(id string, r Record, err error) error {
+		if err != nil || r.Error != "boom" || seen[id] {
+			t.Errorf("unexpected record %s: %+v (%v)", id, r, err)
+		}
+		seen[id] = true
+		return store.Delete(ctx, id)
+	})
+	if err != nil {
+		t.Fatalf("unexpected scan error: %v", err)
+	}
+	if len(seen) != scanPageSize+10 {
+		t.Fatalf("expected every old record across pages, got %d", len(seen))
+	}
+}
+
+func TestRedisStoreEncryptsEncryptedEvents(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	ctx := context.Background()
+	keys := keyring.New()
+	keys.Add("k1", bytes.Repeat([]byte{1}, 32))
+	store := NewRedisStore(client, "events.dlq", WithEncryption(keys))
+
+	record := Record{Message: events.Message{ID: "1", Type: "customer.updated", Payload: "jane@example.com", Encrypted: true}}
+	if err := NewRedisStore(client, "events.dlq").Put(ctx, record); !errors.Is(err, codec.ErrNoSealKeys) {
+		t.Fatalf("expected an encrypted event to need keys, got %v", err)
+	}
+	if err := store.Put(ctx, record); err != nil {
+		t.Fatalf("unexpected put error: %v", err)
+	}
+	entries, err := client.XRange(ctx, "events.dlq", "-", "+").Result()
+	if err != nil || len(entries) != 1 || strings.Contains(entries[0].Values[recordField].(string), "example.com") {
+		t.Fatalf("expected the message to be stored encrypted, got %v (%v)", entries, err)
+	}
+
+	got, err := store.Get(ctx, entries[0].ID)
+	if err != nil {
+		t.Fatalf("unexpected get error: %v", err)
+	}
+	var payload string
+	if err := got.Message.DecodePayload(&payload); err != nil || payload != "jane@example.com" || !got.Message.Encrypted {
+		t.Fatalf("expected the encrypted event back, got %+v (%v)", got.Message, err)
+	}
+}
+
+func TestFencedRedisStore(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	ctx := context.Background()
+	store := NewRedisStore(client, "events.dlq")
+	for range 2 {
+		if err := store.Put(ctx, Record{Message: events.Message{ID: "1", Type: "test"}}); err != nil {
+			t.Fatalf("unexpected put error: %v", err)
+		}
+	}
+	records, _ := store.List(ctx, 0)
+
+	mr.Set("leader", "node-a:1")
+	fenced := store.Fenced("leader", "node-a:1")
+	if err := fenced.Delete(ctx, records[0].ID); err != nil {
+		t.Fatalf("expected the lease holder to delete, got %v", err)
+	}
+	if err := fenced.Delete(ctx, records[0].ID); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+
+	// a new leader took over
+	mr.Set("leader", "node-b:2")
+	if err := fenced.Delete(ctx, records[1].ID); !errors.Is(err, ErrFenced) {
+		t.Fatalf("expected ErrFenced, got %v", err)
+	}
+	if _, err := store.Get(ctx, records[1].ID); err != nil {
+		t.Fatalf("expected the record to be kept, got %v", err)
+	}
+}
@@ -0,0 +1,87 @@
+package dlq
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps records in process memory. It is meant for tests.
+type MemoryStore struct {
+	mu      sync.Mutex
+	records []Record
+	nextID  int
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+func (m *MemoryStore) Put(ctx context.Context, record Record) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextID++
+	record.ID = strconv.Itoa(m.nextID)
+	m.records = append(m.records, record)
+	return nil
+}
+
+func (m *MemoryStore) List(ctx context.Context, limit int64) ([]Record, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := int64(len(m.records))
+	if limit > 0 && limit < n {
+		n = limit
+	}
+	out := make([]Record, n)
+	copy(out, m.records)
+	return out, nil
+}
+
+func (m *MemoryStore) ScanBefore(ctx context.Context, cutoff time.Time, fn func(id string, record Record, err error) error) error {
+	m.mu.Lock()
+	records := slices.Clone(m.records)
+	m.mu.Unlock()
+	for _, r := range records {
+		if !r.FailedAt.Before(cutoff) {
+			continue
+		}
+		if err := fn(r.ID, r, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) Get(ctx context.Context, id string) (Record, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, r := range m.records {
+		if r.ID == id {
+			return r, nil
+		}
+	}
+	return Record{}, ErrNotFound
+}
+
+func (m *MemoryStore) Delete(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, r := range m.records {
+		if r.ID == id {
+			m.records = append(m.records[:i], m.records[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
+
+func (m *MemoryStore) Purge(ctx context.Context) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := int64(len(m.records))
+	m.records = nil
+	return n, nil
+}
@@ -0,0 +1,212 @@
+package dlq
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/codec"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/events"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/keyring"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const recordField = "record"
+
+// scanPageSize is how many entries ScanBefore reads at a time.
+const scanPageSize = 100
+
+// fencedDeleteScript deletes entry ARGV[1] of stream KEYS[1] only while the
+// lease at KEYS[2] holds ARGV[2], and returns -1 otherwise.
+var fencedDeleteScript = redis.NewScript(`
+if redis.call("GET", KEYS[2]) ~= ARGV[2] then
+	return -1
+end
+return redis.call("XDEL", KEYS[1], ARGV[1])`)
+
+// RedisStore keeps records in a Redis stream, so entry IDs double as record
+// IDs and records are naturally ordered by failure time.
+type RedisStore struct {
+	client *redis.Client
+	key    string
+	keys   *keyring.Keyring
+}
+
+type RedisOption func(*RedisStore)
+
+// WithEncryption encrypts the messages of events that were encrypted on the
+// wire under the current key of keys, so they never sit in Redis in the
+// clear, and decrypts them when records are read. Without it such events
+// cannot be stored.
+func WithEncryption(keys *keyring.Keyring) RedisOption {
+	return func(s *RedisStore) { s.keys = keys }
+}
+
+func NewRedisStore(client *redis.Client, key string, opts ...RedisOption) *RedisStore {
+	s := &RedisStore{client: client, key: key}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// redisRecord is a Record as stored. The message of an encrypted event is
+// sealed with codec.Seal instead of stored in the clear.
+type redisRecord struct {
+	Record
+	Message *events.Message `json:"message,omitempty"`
+	Sealed  []byte          `json:"sealed_message,omitempty"`
+}
+
+func (s *RedisStore) Put(ctx context.Context, record Record) error {
+	record.ID = ""
+	stored := redisRecord{Record: record, Message: &record.Message}
+	if record.Message.Encrypted {
+		sealed, err := codec.Seal(record.Message, s.keys)
+		if err != nil {
+			return err
+		}
+		stored.Message, stored.Sealed = nil, sealed
+	}
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return err
+	}
+	return s.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: s.key,
+		Values: map[string]any{recordField: data},
+	}).Err()
+}
+
+func (s *RedisStore) List(ctx context.Context, limit int64) ([]Record, error) {
+	var msgs []redis.XMessage
+	var err error
+	if limit > 0 {
+		msgs, err = s.client.XRangeN(ctx, s.key, "-", "+", limit).Result()
+	} else {
+		msgs, err = s.client.XRange(ctx, s.key, "-", "+").Result()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]Record, 0, len(msgs))
+	for _, msg := range msgs {
+		record, err := s.decodeRecord(msg)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, nil
+}
+
+// ScanBefore pages through the entries whose stream ID, the time Redis stored
+// them, is before cutoff.
+func (s *RedisStore) ScanBefore(ctx context.Context, cutoff time.Time, fn func(id string, record Record, err error) error) error {
+	end := strconv.FormatInt(cutoff.UnixMilli()-1, 10)
+	start := "-"
+	for {
+		msgs, err := s.client.XRangeN(ctx, s.key, start, end, scanPageSize).Result()
+		if err != nil {
+			return err
+		}
+		for _, msg := range msgs {
+			record, err := s.decodeRecord(msg)
+			if err := fn(msg.ID, record, err); err != nil {
+				return err
+			}
+		}
+		if len(msgs) < scanPageSize {
+			return nil
+		}
+		start = "(" + msgs[len(msgs)-1].ID
+	}
+}
+
+func (s *RedisStore) Get(ctx context.Context, id string) (Record, error) {
+	msgs, err := s.client.XRange(ctx, s.key, id, id).Result()
+	if err != nil {
+		return Record{}, err
+	}
+	if len(msgs) == 0 {
+		return Record{}, ErrNotFound
+	}
+	return s.decodeRecord(msgs[0])
+}
+
+func (s *RedisStore) Delete(ctx context.Context, id string) error {
+	n, err := s.client.XDel(ctx, s.key, id).Result()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *RedisStore) Purge(ctx context.Context) (int64, error) {
+	n, err := s.client.XLen(ctx, s.key).Result()
+	if err != nil {
+		return 0, err
+	}
+	if err := s.client.Del(ctx, s.key).Err(); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+func (s *RedisStore) decodeRecord(msg redis.XMessage) (Record, error) {
+	data, ok := msg.Values[recordField].(string)
+	if !ok {
+		return Record{}, fmt.Errorf("dead-letter entry %s has no %s field", msg.ID, recordField)
+	}
+	var stored redisRecord
+	if err := json.Unmarshal([]byte(data), &stored); err != nil {
+		return Record{}, fmt.Errorf("dead-letter entry %s: %w", msg.ID, err)
+	}
+	record := stored.Record
+	if stored.Message != nil {
+		record.Message = *stored.Message
+	}
+	if stored.Sealed != nil {
+		if err := codec.Open(stored.Sealed, s.keys, &record.Message); err != nil {
+			return Record{}, fmt.Errorf("dead-letter entry %s: %w", msg.ID, err)
+		}
+	}
+	record.ID = msg.ID
+	return record, nil
+}
+
+// Fenced returns s with Delete guarded by a leader lease: records are only
+// deleted while the lease at leaseKey holds leaseValue (see
+// leader.LeaseValue), checked in the same script as the delete, and Delete
+// fails with ErrFenced otherwise. A leader that lost its lease without
+// noticing cannot delete records once another one took over.
+func (s *RedisStore) Fenced(leaseKey, leaseValue string) Store {
+	return &fencedStore{RedisStore: s, leaseKey: leaseKey, leaseValue: leaseValue}
+}
+
+type fencedStore struct {
+	*RedisStore
+	leaseKey   string
+	leaseValue string
+}
+
+func (s *fencedStore) Delete(ctx context.Context, id string) error {
+	n, err := fencedDeleteScript.Run(ctx, s.client, []string{s.key, s.leaseKey}, id, s.leaseValue).Int64()
+	if err != nil {
+		return err
+	}
+	switch n {
+	case -1:
+		return ErrFenced
+	case 0:
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package events
+
+import "encoding/json"
+
+// Envelope is a message whose payload has been decoded into T. The embedded
+// Message still holds the payload as received.
+type Envelope[T any] struct {
+	Message
+	Payload T
+}
+
+// PayloadDecoder is implemented by the raw payloads of non-JSON codecs.
+type PayloadDecoder interface {
+	DecodePayload(v any) error
+}
+
+// DecodePayload decodes the payload into v, which must be a pointer.
+func (m Message) DecodePayload(v any) error {
+	switch raw := m.Payload.(type) {
+	case json.RawMessage:
+		return json.Unmarshal(raw, v)
+	case PayloadDecoder:
+		return raw.DecodePayload(v)
+	}
+	// a payload set in process is a Go value, so round-trip it
+	data, err := json.Marshal(m.Payload)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// Open decodes the payload of m into an Envelope.
+func Open[T any](m Message) (Envelope[T], error) {
+	env := Envelope[T]{Message: m}
+	if err := m.DecodePayload(&env.Payload); err != nil {
+		return env, err
+	}
+	return env, nil
+}
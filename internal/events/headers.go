@@ -0,0 +1,75 @@
+package events
+
+import "maps"
+
+// Well-known header keys.
+const (
+	HeaderCorrelationID = "correlation-id"
+	HeaderCausationID   = "causation-id"
+	HeaderTenantID      = "tenant-id"
+	HeaderContentType   = "content-type"
+	HeaderSchemaVersion = "schema-version"
+	HeaderTraceParent   = "traceparent"
+	HeaderTraceState    = "tracestate"
+	HeaderReplyTo       = "reply-to"
+)
+
+// Header returns the value of a header, or an empty string.
+func (m Message) Header(key string) string {
+	return m.Headers[key]
+}
+
+// SetHeader sets a header, or removes it when value is empty. The map is
+// copied first, so other copies of the message, such as the one a parallel
+// handler holds, never see the change.
+func (m *Message) SetHeader(key, value string) {
+	headers := maps.Clone(m.Headers)
+	if value == "" {
+		delete(headers, key)
+	} else {
+		if headers == nil {
+			headers = make(map[string]string, 1)
+		}
+		headers[key] = value
+	}
+	if len(headers) == 0 {
+		headers = nil
+	}
+	m.Headers = headers
+}
+
+// CorrelationID identifies the conversation or request the event belongs to.
+func (m Message) CorrelationID() string { return m.Header(HeaderCorrelationID) }
+
+func (m *Message) SetCorrelationID(id string) { m.SetHeader(HeaderCorrelationID, id) }
+
+// CausationID is the ID of the event that caused this one.
+func (m Message) CausationID() string { return m.Header(HeaderCausationID) }
+
+func (m *Message) SetCausationID(id string) { m.SetHeader(HeaderCausationID, id) }
+
+func (m Message) TenantID() string { return m.Header(HeaderTenantID) }
+
+func (m *Message) SetTenantID(id string) { m.SetHeader(HeaderTenantID, id) }
+
+func (m Message) SchemaVersion() string { return m.Header(HeaderSchemaVersion) }
+
+func (m *Message) SetSchemaVersion(v string) { m.SetHeader(HeaderSchemaVersion, v) }
+
+// ReplyTo is the channel subscribers acknowledge the event on once they have
+// handled it. Events without it are not acknowledged.
+func (m Message) ReplyTo() string { return m.Header(HeaderReplyTo) }
+
+func (m *Message) SetReplyTo(channel string) { m.SetHeader(HeaderReplyTo, channel) }
+
+// CausedBy marks the event as a consequence of parent: the causation ID
+// becomes the parent's ID and the correlation ID is inherited, or started
+// from the parent's ID when the parent has none.
+func (m *Message) CausedBy(parent Message) {
+	m.SetCausationID(parent.ID)
+	correlation := parent.CorrelationID()
+	if correlation == "" {
+		correlation = parent.ID
+	}
+	m.SetCorrelationID(correlation)
+}
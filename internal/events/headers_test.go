@@ -0,0 +1,37 @@
+package events
+
+import "testing"
+
+func TestSetHeaderCopiesMap(t *testing.T) {
+	original := Message{Headers: map[string]string{HeaderTenantID: "acme"}}
+	copied := original
+	copied.SetCorrelationID("c-1")
+
+	if original.CorrelationID() != "" {
+		t.Fatal("expected the original message to be unchanged")
+	}
+	if copied.CorrelationID() != "c-1" || copied.TenantID() != "acme" {
+		t.Fatalf("unexpected headers %v", copied.Headers)
+	}
+
+	copied.SetCorrelationID("")
+	copied.SetTenantID("")
+	if copied.Headers != nil {
+		t.Fatalf("expected empty headers to be nil, got %v", copied.Headers)
+	}
+}
+
+func TestCausedBy(t *testing.T) {
+	root := Message{ID: "1"}
+	child := Message{ID: "2"}
+	child.CausedBy(root)
+	if child.CausationID() != "1" || child.CorrelationID() != "1" {
+		t.Fatalf("expected causation and correlation 1, got %v", child.Headers)
+	}
+
+	grandchild := Message{ID: "3"}
+	grandchild.CausedBy(child)
+	if grandchild.CausationID() != "2" || grandchild.CorrelationID() != "1" {
+		t.Fatalf("expected causation 2 and correlation 1, got %v", grandchild.Headers)
+	}
+}
@@ -1,11 +1,65 @@
 package events
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 type Message struct {
 	ID        string    `json:"id"`
 	Type      string    `json:"type"`
 	Source    string    `json:"source"`
 	Timestamp time.Time `json:"timestamp"`
-	Payload   any       `json:"payload"`
+	// Payload is whatever the publisher set. A decoded message holds the
+	// payload raw, as json.RawMessage or a codec's PayloadDecoder, until a
+	// handler decodes it with DecodePayload, so events nobody consumes are
+	// never fully parsed.
+	Payload any `json:"payload"`
+	// Key optionally identifies the entity the event is about. Processors
+	// partitioned with processor.MessageKey handle events with the same key
+	// in order.
+	Key string `json:"key,omitempty"`
+	// Headers carry metadata such as correlation IDs and trace context. Use
+	// SetHeader, or the typed helpers, rather than writing to the map.
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// Channel and Pattern record where the message was received. They are
+	// set by the subscriber and never sent on the wire; Pattern is empty
+	// unless the message matched a PSUBSCRIBE pattern.
+	Channel string `json:"-"`
+	Pattern string `json:"-"`
+	// Encrypted is set by the subscriber when the message was encrypted on
+	// the wire. Its payload must not be logged; see Redact.
+	Encrypted bool `json:"-"`
+}
+
+// Redacted replaces values that must not be logged.
+const Redacted = "[redacted]"
+
+// Redact returns v, or Redacted if m was encrypted. Use it for the payload,
+// and for errors or panic values that may quote it, when logging:
+//
+//	logger.Info("handled", "payload", event.Redact(event.Payload))
+func (m Message) Redact(v any) any {
+	if m.Encrypted {
+		return Redacted
+	}
+	return v
+}
+
+// UnmarshalJSON decodes the envelope but keeps the payload as raw bytes.
+func (m *Message) UnmarshalJSON(data []byte) error {
+	type message Message // without the UnmarshalJSON method
+	aux := struct {
+		*message
+		Payload json.RawMessage `json:"payload"`
+	}{message: (*message)(m)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	m.Payload = nil
+	if len(aux.Payload) > 0 && string(aux.Payload) != "null" {
+		m.Payload = aux.Payload
+	}
+	return nil
 }
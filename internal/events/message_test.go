@@ -0,0 +1,67 @@
+package events
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestUnmarshalKeepsRawPayload(t *testing.T) {
+	data := []byte(`{"id":"1","type":"order.created","source":"test","timestamp":"2026-02-22T10:00:00Z","key":"o-1","headers":{"tenant-id":"acme"},"payload":{"order_id":"o-1","total":9.5}}`)
+
+	var m Message
+	if err := json.Unmarshal(data, &m); err != nil {
+		t.Fatalf("unexpected unmarshal error: %v", err)
+	}
+	if m.ID != "1" || m.Type != "order.created" || m.Key != "o-1" || m.TenantID() != "acme" || !m.Timestamp.Equal(time.Date(2026, 2, 22, 10, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected envelope %+v", m)
+	}
+	raw, ok := m.Payload.(json.RawMessage)
+	if !ok || string(raw) != `{"order_id":"o-1","total":9.5}` {
+		t.Fatalf("expected the raw payload, got %#v", m.Payload)
+	}
+
+	var payload struct {
+		OrderID string  `json:"order_id"`
+		Total   float64 `json:"total"`
+	}
+	if err := m.DecodePayload(&payload); err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+	if payload.OrderID != "o-1" || payload.Total != 9.5 {
+		t.Fatalf("unexpected payload %+v", payload)
+	}
+
+	// re-encoding writes the raw bytes back unchanged
+	out, err := json.Marshal(m)
+	if err != nil {
+		t.Fatalf("unexpected marshal error: %v", err)
+	}
+	var again Message
+	if err := json.Unmarshal(out, &again); err != nil {
+		t.Fatalf("unexpected unmarshal error: %v", err)
+	}
+	if string(again.Payload.(json.RawMessage)) != string(raw) {
+		t.Fatalf("expected payload to survive a round trip, got %s", again.Payload)
+	}
+}
+
+func TestUnmarshalNullPayload(t *testing.T) {
+	for _, data := range []string{`{"id":"1"}`, `{"id":"1","payload":null}`} {
+		var m Message
+		if err := json.Unmarshal([]byte(data), &m); err != nil {
+			t.Fatalf("unexpected unmarshal error: %v", err)
+		}
+		if m.Payload != nil {
+			t.Fatalf("expected no payload for %s, got %#v", data, m.Payload)
+		}
+	}
+}
+
+func TestDecodeInProcessPayload(t *testing.T) {
+	m := Message{Payload: map[string]any{"count": 3}}
+	var payload struct{ Count int }
+	if err := m.DecodePayload(&payload); err != nil || payload.Count != 3 {
+		t.Fatalf("expected count 3, got %+v (%v)", payload, err)
+	}
+}
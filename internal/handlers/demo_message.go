@@ -20,7 +20,7 @@ func (h *DemoMessageHandler) Handle(ctx context.Context, event events.Message) e
 		"demo.message handled",
 		"id", event.ID,
 		"source", event.Source,
-		"payload", event.Payload,
+		"payload", event.Redact(event.Payload),
 	)
 	return nil
 }
@@ -4,6 +4,7 @@ import (
 	"context"
 	"log/slog"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/events"
@@ -33,3 +34,16 @@ func TestHandle(t *testing.T) {
 		t.Errorf("Expected no error, got %v", err)
 	}
 }
+
+func TestHandleRedactsEncryptedPayload(t *testing.T) {
+	var buf strings.Builder
+	handler := NewDemoMessageHandler(slog.New(slog.NewTextHandler(&buf, nil)))
+
+	event := events.Message{ID: "123", Payload: "secret-payload", Encrypted: true}
+	if err := handler.Handle(context.Background(), event); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if strings.Contains(buf.String(), "secret-payload") || !strings.Contains(buf.String(), "payload="+events.Redacted) {
+		t.Errorf("Expected payload to be redacted, got %s", buf.String())
+	}
+}
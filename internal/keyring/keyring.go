@@ -0,0 +1,210 @@
+// Package keyring holds the named secret keys used to sign and verify
+// messages. One key is current and used for new messages; the others stay
+// valid for messages already in flight, so keys can be rotated without
+// downtime.
+package keyring
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var (
+	ErrNoKey      = errors.New("no current key")
+	ErrUnknownKey = errors.New("unknown key")
+)
+
+// Keyring is safe for concurrent use.
+type Keyring struct {
+	mu      sync.RWMutex
+	keys    map[string][]byte
+	current string
+}
+
+func New() *Keyring {
+	return &Keyring{keys: make(map[string][]byte)}
+}
+
+// Add adds key under id, replacing any key with the same ID. The first key
+// added becomes current.
+func (k *Keyring) Add(id string, key []byte) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.keys[id] = key
+	if k.current == "" {
+		k.current = id
+	}
+}
+
+// Remove retires the key id. Messages signed with it are no longer accepted.
+func (k *Keyring) Remove(id string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	delete(k.keys, id)
+	if k.current == id {
+		k.current = ""
+	}
+}
+
+// SetCurrent selects the key used for new messages.
+func (k *Keyring) SetCurrent(id string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if _, ok := k.keys[id]; !ok {
+		return fmt.Errorf("%w %q", ErrUnknownKey, id)
+	}
+	k.current = id
+	return nil
+}
+
+// Current returns the ID and key used for new messages.
+func (k *Keyring) Current() (string, []byte, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	if k.current == "" {
+		return "", nil, ErrNoKey
+	}
+	return k.current, k.keys[k.current], nil
+}
+
+// Key returns the key with the given ID.
+func (k *Keyring) Key(id string) ([]byte, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	key, ok := k.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownKey, id)
+	}
+	return key, nil
+}
+
+// IDs returns the IDs of all keys, sorted.
+func (k *Keyring) IDs() []string {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	ids := make([]string, 0, len(k.keys))
+	for id := range k.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Len returns the number of keys.
+func (k *Keyring) Len() int {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	return len(k.keys)
+}
+
+// FromEnv loads keys from <prefix>_KEYS, a comma-separated list of
+// id=base64key pairs. <prefix>_KEY_ID selects the current key; it defaults to
+// the first key listed. The keyring is empty when <prefix>_KEYS is unset.
+func FromEnv(prefix string) (*Keyring, error) {
+	k := New()
+	if err := k.Parse(os.Getenv(prefix + "_KEYS")); err != nil {
+		return nil, fmt.Errorf("%s_KEYS: %w", prefix, err)
+	}
+	if id := os.Getenv(prefix + "_KEY_ID"); id != "" {
+		if err := k.SetCurrent(id); err != nil {
+			return nil, fmt.Errorf("%s_KEY_ID: %w", prefix, err)
+		}
+	}
+	return k, nil
+}
+
+// FromDir loads keys from dir; see LoadDir.
+func FromDir(dir string) (*Keyring, error) {
+	k := New()
+	if err := k.LoadDir(dir); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Load loads keys from the directory in <prefix>_KEY_DIR when it is set, and
+// from the environment as described for FromEnv otherwise.
+func Load(prefix string) (*Keyring, error) {
+	if dir := os.Getenv(prefix + "_KEY_DIR"); dir != "" {
+		return FromDir(dir)
+	}
+	return FromEnv(prefix)
+}
+
+// LoadDir replaces the keys with those in dir, where every <id>.key file
+// holds a base64 key. A file named current holds the ID of the current key;
+// without it the last ID in sorted order is current, so date-based IDs pick
+// the newest key. Reloading a directory in which a new key was added, and
+// later an old one removed, rotates keys without a restart.
+func (k *Keyring) LoadDir(dir string) error {
+	files, err := filepath.Glob(filepath.Join(dir, "*.key"))
+	if err != nil {
+		return err
+	}
+	keys := make(map[string][]byte, len(files))
+	ids := make([]string, 0, len(files))
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		id := strings.TrimSuffix(filepath.Base(file), ".key")
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil {
+			return fmt.Errorf("key %q: %w", id, err)
+		}
+		if len(key) == 0 {
+			return fmt.Errorf("key %q is empty", id)
+		}
+		keys[id] = key
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return fmt.Errorf("no *.key files in %s", dir)
+	}
+	sort.Strings(ids)
+	current := ids[len(ids)-1]
+	if data, err := os.ReadFile(filepath.Join(dir, "current")); err == nil {
+		current = strings.TrimSpace(string(data))
+		if _, ok := keys[current]; !ok {
+			return fmt.Errorf("current: %w %q", ErrUnknownKey, current)
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.keys = keys
+	k.current = current
+	return nil
+}
+
+// Parse adds the keys in a comma-separated list of id=base64key pairs.
+func (k *Keyring) Parse(list string) error {
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, encoded, ok := strings.Cut(entry, "=")
+		if !ok || id == "" {
+			return fmt.Errorf("invalid key entry %q, want id=base64key", entry)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return fmt.Errorf("key %q: %w", id, err)
+		}
+		if len(key) == 0 {
+			return fmt.Errorf("key %q is empty", id)
+		}
+		k.Add(id, key)
+	}
+	return nil
+}
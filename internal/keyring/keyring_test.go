@@ -0,0 +1,108 @@
+package keyring
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestKeyringRotation(t *testing.T) {
+	k := New()
+	if _, _, err := k.Current(); !errors.Is(err, ErrNoKey) {
+		t.Fatalf("expected ErrNoKey, got %v", err)
+	}
+
+	k.Add("2024", []byte("old"))
+	k.Add("2025", []byte("new"))
+	if id, _, _ := k.Current(); id != "2024" {
+		t.Fatalf("expected the first key to be current, got %s", id)
+	}
+	if err := k.SetCurrent("2025"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if id, key, _ := k.Current(); id != "2025" || string(key) != "new" {
+		t.Fatalf("unexpected current key %s=%s", id, key)
+	}
+	if key, err := k.Key("2024"); err != nil || string(key) != "old" {
+		t.Fatalf("expected the old key to stay valid, got %q (%v)", key, err)
+	}
+
+	k.Remove("2024")
+	if _, err := k.Key("2024"); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("expected ErrUnknownKey, got %v", err)
+	}
+	if err := k.SetCurrent("2024"); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("expected ErrUnknownKey, got %v", err)
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv("TEST_KEYS", "a=YWFh, b=YmJi")
+	t.Setenv("TEST_KEY_ID", "b")
+	k, err := FromEnv("TEST")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Equal(k.IDs(), []string{"a", "b"}) {
+		t.Fatalf("unexpected keys %v", k.IDs())
+	}
+	if id, key, _ := k.Current(); id != "b" || string(key) != "bbb" {
+		t.Fatalf("unexpected current key %s=%s", id, key)
+	}
+
+	for _, keys := range []string{"a", "a=!!", "a="} {
+		t.Setenv("TEST_KEYS", keys)
+		t.Setenv("TEST_KEY_ID", "")
+		if _, err := FromEnv("TEST"); err == nil {
+			t.Fatalf("expected %q to be rejected", keys)
+		}
+	}
+
+	t.Setenv("TEST_KEYS", "")
+	if k, err := FromEnv("TEST"); err != nil || k.Len() != 0 {
+		t.Fatalf("expected an empty keyring, got %v (%v)", k.IDs(), err)
+	}
+}
+
+func TestLoadDir(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("2025-01.key", "YWFh\n")
+	write("2025-06.key", "YmJi\n")
+	write("README", "ignored")
+
+	k, err := FromDir(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if id, key, _ := k.Current(); id != "2025-06" || string(key) != "bbb" {
+		t.Fatalf("expected the newest key to be current, got %s=%s", id, key)
+	}
+
+	// rotate back with the current file and retire the newest key
+	write("current", "2025-01\n")
+	if err := k.LoadDir(dir); err != nil {
+		t.Fatalf("unexpected reload error: %v", err)
+	}
+	if id, _, _ := k.Current(); id != "2025-01" {
+		t.Fatalf("expected current file to select 2025-01, got %s", id)
+	}
+
+	write("current", "2024-01")
+	if err := k.LoadDir(dir); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("expected ErrUnknownKey, got %v", err)
+	}
+	if id, _, _ := k.Current(); id != "2025-01" {
+		t.Fatalf("expected a failed reload to keep the keys, got current %s", id)
+	}
+	if _, err := FromDir(t.TempDir()); err == nil {
+		t.Fatal("expected an empty directory to be rejected")
+	}
+}
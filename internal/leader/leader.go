@@ -0,0 +1,245 @@
+// Package leader elects one instance of a fleet to run cluster-wide tasks.
+// The leader holds a lease, a Redis key set with SET NX PX and renewed well
+// before it expires. Every new lease gets a fencing token from INCR, larger
+// than any token handed out before, so writes made by a leader that lost its
+// lease without noticing can be told apart from the current leader's.
+package leader
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrNotLeader is returned by renewal when the lease is held by someone else
+// or has expired.
+var ErrNotLeader = errors.New("not the leader")
+
+var (
+	// acquire sets the lease to "<id>:<token>" if it is free and returns the
+	// new token.
+	acquireScript = redis.NewScript(`
+if not redis.call("SET", KEYS[1], ARGV[1], "NX", "PX", ARGV[2]) then
+	return false
+end
+local token = redis.call("INCR", KEYS[2])
+redis.call("SET", KEYS[1], ARGV[1] .. ":" .. token, "PX", ARGV[2])
+return token`)
+	renewScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+	resignScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+)
+
+// Elector campaigns for the lease under one key on behalf of one candidate.
+type Elector struct {
+	client     *redis.Client
+	key        string
+	id         string
+	logger     *slog.Logger
+	ttl        time.Duration
+	renewEvery time.Duration
+	retryEvery time.Duration
+	onElected  func(ctx context.Context, token int64)
+	onRevoked  func()
+
+	mu    sync.Mutex
+	token int64 // 0 while not leading
+}
+
+type Option func(*Elector)
+
+// WithTTL sets how long a lease lasts without renewal, and so how long a
+// crashed leader blocks a new election. The default is 15s.
+func WithTTL(ttl time.Duration) Option {
+	return func(e *Elector) { e.ttl = ttl }
+}
+
+// WithRenewInterval sets how often the leader renews its lease, and how
+// often followers try to take it. The default is a third of the TTL.
+func WithRenewInterval(d time.Duration) Option {
+	return func(e *Elector) {
+		e.renewEvery = d
+		e.retryEvery = d
+	}
+}
+
+// OnElected is called, in its own goroutine, when the candidate becomes
+// leader. ctx is cancelled as soon as leadership is lost, and fn must return
+// then; token is the fencing token of the lease.
+func OnElected(fn func(ctx context.Context, token int64)) Option {
+	return func(e *Elector) { e.onElected = fn }
+}
+
+// OnRevoked is called when leadership is lost or given up, after the
+// OnElected callback has returned.
+func OnRevoked(fn func()) Option {
+	return func(e *Elector) { e.onRevoked = fn }
+}
+
+// New creates an elector for the lease at key. id identifies the candidate,
+// such as the server ID, and must be unique within the fleet. The fencing
+// counter is kept at key + ":token".
+func New(client *redis.Client, key, id string, logger *slog.Logger, opts ...Option) *Elector {
+	e := &Elector{
+		client:    client,
+		key:       key,
+		id:        id,
+		logger:    logger,
+		ttl:       15 * time.Second,
+		onElected: func(context.Context, int64) {},
+		onRevoked: func() {},
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	if e.renewEvery <= 0 {
+		e.renewEvery = e.ttl / 3
+		e.retryEvery = e.ttl / 3
+	}
+	return e
+}
+
+// IsLeader reports whether the candidate holds the lease.
+func (e *Elector) IsLeader() bool {
+	_, ok := e.Token()
+	return ok
+}
+
+// Token returns the fencing token of the current lease.
+func (e *Elector) Token() (int64, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.token, e.token != 0
+}
+
+// LeaseValue returns the value the lease holds while candidate id leads
+// with token. Scripts that compare the lease against it only write while
+// that lease is current.
+func LeaseValue(id string, token int64) string {
+	return id + ":" + strconv.FormatInt(token, 10)
+}
+
+// Leader returns the ID of the current leader and its fencing token, or an
+// empty ID if there is none.
+func Leader(ctx context.Context, client *redis.Client, key string) (string, int64, error) {
+	value, err := client.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", 0, nil
+	}
+	if err != nil {
+		return "", 0, err
+	}
+	i := strings.LastIndexByte(value, ':')
+	if i < 0 {
+		return value, 0, nil // the lease is being set up
+	}
+	token, err := strconv.ParseInt(value[i+1:], 10, 64)
+	if err != nil {
+		return "", 0, err
+	}
+	return value[:i], token, nil
+}
+
+// Run campaigns for the lease until ctx is cancelled, then gives it up if
+// held.
+func (e *Elector) Run(ctx context.Context) error {
+	for {
+		token, err := e.acquire(ctx)
+		if err != nil && ctx.Err() == nil {
+			e.logger.Warn("leader election failed", "key", e.key, "error", err)
+		}
+		if token != 0 {
+			e.lead(ctx, token)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(e.retryEvery):
+		}
+	}
+}
+
+func (e *Elector) acquire(ctx context.Context) (int64, error) {
+	token, err := acquireScript.Run(ctx, e.client, []string{e.key, e.key + ":token"}, e.id, e.ttl.Milliseconds()).Int64()
+	if errors.Is(err, redis.Nil) {
+		return 0, nil
+	}
+	return token, err
+}
+
+// lead holds the lease until renewal fails or ctx is cancelled.
+func (e *Elector) lead(ctx context.Context, token int64) {
+	value := LeaseValue(e.id, token)
+	e.mu.Lock()
+	e.token = token
+	e.mu.Unlock()
+	e.logger.Info("elected leader", "key", e.key, "token", token)
+
+	leaderCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		e.onElected(leaderCtx, token)
+	}()
+
+	err := e.keep(ctx, value)
+	cancel()
+	<-done
+	e.mu.Lock()
+	e.token = 0
+	e.mu.Unlock()
+	e.onRevoked()
+
+	if ctx.Err() != nil {
+		// hand over right away instead of making the fleet wait for expiry
+		resignCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), e.renewEvery)
+		defer cancel()
+		if err := resignScript.Run(resignCtx, e.client, []string{e.key}, value).Err(); err != nil {
+			e.logger.Warn("failed to give up leadership", "key", e.key, "error", err)
+		}
+		e.logger.Info("gave up leadership", "key", e.key, "token", token)
+		return
+	}
+	e.logger.Warn("lost leadership", "key", e.key, "token", token, "error", err)
+}
+
+// keep renews the lease every renew interval. It returns nil when ctx is
+// cancelled and the renewal error otherwise. A renewal that cannot complete
+// before the next one is due counts as failed, so leadership is dropped
+// while the lease is still valid rather than after it expired.
+func (e *Elector) keep(ctx context.Context, value string) error {
+	ticker := time.NewTicker(e.renewEvery)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		renewCtx, cancel := context.WithTimeout(ctx, e.renewEvery)
+		renewed, err := renewScript.Run(renewCtx, e.client, []string{e.key}, value, e.ttl.Milliseconds()).Int64()
+		cancel()
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if renewed == 0 {
+			return ErrNotLeader
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package leader
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+type candidate struct {
+	*Elector
+	elected chan int64
+	revoked chan struct{}
+	lost    chan struct{} // closed by the OnElected callback when its ctx ends
+}
+
+func newCandidate(t *testing.T, client *redis.Client, id string) *candidate {
+	t.Helper()
+	c := &candidate{elected: make(chan int64, 4), revoked: make(chan struct{}, 4), lost: make(chan struct{}, 4)}
+	c.Elector = New(client, "leader", id, slog.New(slog.DiscardHandler),
+		WithTTL(time.Second),
+		WithRenewInterval(20*time.Millisecond),
+		OnElected(func(ctx context.Context, token int64) {
+			c.elected <- token
+			<-ctx.Done()
+			c.lost <- struct{}{}
+		}),
+		OnRevoked(func() { c.revoked <- struct{}{} }),
+	)
+	return c
+}
+
+func receive[T any](t *testing.T, ch <-chan T, what string) T {
+	t.Helper()
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(2 * time.Second):
+		t.Fatalf("timed out waiting for %s", what)
+		panic("unreachable")
+	}
+}
+
+func TestElectionAndHandover(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+	a := newCandidate(t, client, "a")
+	ctxA, stopA := context.WithCancel(context.Background())
+	doneA := make(chan error, 1)
+	go func() { doneA <- a.Run(ctxA) }()
+	if token := receive(t, a.elected, "a to be elected"); token != 1 {
+		t.Fatalf("expected fencing token 1, got %d", token)
+	}
+	if !a.IsLeader() {
+		t.Fatal("expected a to report leadership")
+	}
+	if id, token, err := Leader(context.Background(), client, "leader"); err != nil || id != "a" || token != 1 {
+		t.Fatalf("expected leader a with token 1, got %s %d (%v)", id, token, err)
+	}
+
+	b := newCandidate(t, client, "b")
+	ctxB, stopB := context.WithCancel(context.Background())
+	defer stopB()
+	go func() { _ = b.Run(ctxB) }()
+	time.Sleep(100 * time.Millisecond)
+	if b.IsLeader() {
+		t.Fatal("expected b to follow while a holds the lease")
+	}
+
+	stopA()
+	receive(t, a.lost, "a's leader context to end")
+	receive(t, a.revoked, "a to be revoked")
+	if err := receive(t, doneA, "a to stop"); err != nil {
+		t.Fatalf("unexpected run error: %v", err)
+	}
+	if token := receive(t, b.elected, "b to take over"); token != 2 {
+		t.Fatalf("expected fencing token 2, got %d", token)
+	}
+}
+
+func TestLeadershipLossIsDetected(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+
+	a := newCandidate(t, client, "a")
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	go func() { _ = a.Run(ctx) }()
+	receive(t, a.elected, "a to be elected")
+
+	// the lease expired, e.g. during a long pause, and someone else took it
+	if err := mr.Set("leader", "b:7"); err != nil {
+		t.Fatal(err)
+	}
+	receive(t, a.lost, "a's leader context to end")
+	receive(t, a.revoked, "a to be revoked")
+	if a.IsLeader() {
+		t.Fatal("expected a to step down")
+	}
+}
+
+func TestRenewalFailureRevokes(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr(), MaxRetries: -1})
+
+	a := newCandidate(t, client, "a")
+	ctx, stop := context.WithCancel(context.Background())
+	defer stop()
+	go func() { _ = a.Run(ctx) }()
+	receive(t, a.elected, "a to be elected")
+
+	mr.Close()
+	receive(t, a.revoked, "a to be revoked once Redis is unreachable")
+}
@@ -0,0 +1,222 @@
+// Package membership keeps a registry of the live subscribers in Redis. Every
+// subscriber announces itself under its own key with a TTL and refreshes it
+// with heartbeats, so a crashed subscriber drops out once its key expires.
+package membership
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Member describes one subscriber.
+type Member struct {
+	ID           string           `json:"id"`
+	Version      string           `json:"version"`
+	Channels     []string         `json:"channels,omitempty"`
+	Patterns     []string         `json:"patterns,omitempty"`
+	HandlerTypes []string         `json:"handler_types,omitempty"`
+	Metrics      map[string]int64 `json:"metrics,omitempty"`
+	StartedAt    time.Time        `json:"started_at"`
+	LastSeen     time.Time        `json:"last_seen"`
+}
+
+// Listens reports whether m is subscribed to channel, directly or through a
+// pattern, which is what PUBLISH counts as a receiver.
+func (m Member) Listens(channel string) bool {
+	if slices.Contains(m.Channels, channel) {
+		return true
+	}
+	for _, pattern := range m.Patterns {
+		if match(pattern, channel) {
+			return true
+		}
+	}
+	return false
+}
+
+// Membership reads and writes the registry under a key prefix. Members are
+// stored at <prefix>:<id> and indexed in the sorted set <prefix>, scored by
+// when they were last seen.
+type Membership struct {
+	client *redis.Client
+	prefix string
+	ttl    time.Duration
+}
+
+// New creates a registry whose members expire ttl after their last
+// heartbeat.
+func New(client *redis.Client, prefix string, ttl time.Duration) *Membership {
+	return &Membership{client: client, prefix: prefix, ttl: ttl}
+}
+
+func (m *Membership) key(id string) string {
+	return m.prefix + ":" + id
+}
+
+// Announce registers member, or refreshes it, and stamps LastSeen.
+func (m *Membership) Announce(ctx context.Context, member Member) error {
+	member.LastSeen = time.Now()
+	data, err := json.Marshal(member)
+	if err != nil {
+		return err
+	}
+	_, err = m.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Set(ctx, m.key(member.ID), data, m.ttl)
+		pipe.ZAdd(ctx, m.prefix, redis.Z{Score: float64(member.LastSeen.UnixMilli()), Member: member.ID})
+		return nil
+	})
+	return err
+}
+
+// Leave removes the member with the given ID.
+func (m *Membership) Leave(ctx context.Context, id string) error {
+	_, err := m.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, m.key(id))
+		pipe.ZRem(ctx, m.prefix, id)
+		return nil
+	})
+	return err
+}
+
+// Members returns the live members sorted by ID. Members whose key expired
+// are dropped from the index.
+func (m *Membership) Members(ctx context.Context) ([]Member, error) {
+	ids, err := m.client.ZRange(ctx, m.prefix, 0, -1).Result()
+	if err != nil || len(ids) == 0 {
+		return nil, err
+	}
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = m.key(id)
+	}
+	values, err := m.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	members := make([]Member, 0, len(ids))
+	var expired []any
+	for i, v := range values {
+		data, ok := v.(string)
+		if !ok {
+			expired = append(expired, ids[i])
+			continue
+		}
+		var member Member
+		if err := json.Unmarshal([]byte(data), &member); err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+	if len(expired) > 0 {
+		if err := m.client.ZRem(ctx, m.prefix, expired...).Err(); err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].ID < members[j].ID })
+	return members, nil
+}
+
+// Run announces the member returned by describe every interval until ctx is
+// cancelled, then leaves. describe is called for every heartbeat, so the
+// member can report current metrics.
+func (m *Membership) Run(ctx context.Context, interval time.Duration, logger *slog.Logger, describe func() Member) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	id := ""
+	for {
+		member := describe()
+		id = member.ID
+		if err := m.Announce(ctx, member); err != nil && ctx.Err() == nil {
+			logger.Warn("membership heartbeat failed", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			// the context is done, but leaving must still reach Redis
+			leaveCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), interval)
+			defer cancel()
+			if err := m.Leave(leaveCtx, id); err != nil {
+				logger.Warn("failed to leave membership", "error", err)
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// match reports whether channel matches a Redis glob pattern, with *, ?,
+// [set] and backslash escapes as PSUBSCRIBE understands them.
+func match(pattern, channel string) bool {
+	for len(pattern) > 0 {
+		switch pattern[0] {
+		case '*':
+			for i := len(channel); i >= 0; i-- {
+				if match(pattern[1:], channel[i:]) {
+					return true
+				}
+			}
+			return false
+		case '?':
+			if len(channel) == 0 {
+				return false
+			}
+		case '[':
+			end := 1
+			if end < len(pattern) && pattern[end] == '^' {
+				end++
+			}
+			// a ] right after the opening bracket is part of the set
+			if end < len(pattern) && pattern[end] == ']' {
+				end++
+			}
+			for end < len(pattern) && pattern[end] != ']' {
+				end++
+			}
+			if len(channel) == 0 || end == len(pattern) {
+				return false
+			}
+			if !inSet(pattern[1:end], channel[0]) {
+				return false
+			}
+			pattern = pattern[end:]
+		case '\\':
+			if len(pattern) > 1 {
+				pattern = pattern[1:]
+			}
+			fallthrough
+		default:
+			if len(channel) == 0 || pattern[0] != channel[0] {
+				return false
+			}
+		}
+		pattern, channel = pattern[1:], channel[1:]
+	}
+	return len(channel) == 0
+}
+
+func inSet(set string, c byte) bool {
+	negate := len(set) > 0 && set[0] == '^'
+	if negate {
+		set = set[1:]
+	}
+	found := false
+	for i := 0; i < len(set); i++ {
+		if i+2 < len(set) && set[i+1] == '-' {
+			lo, hi := set[i], set[i+2]
+			if lo > hi {
+				lo, hi = hi, lo
+			}
+			found = found || (lo <= c && c <= hi)
+			i += 2
+			continue
+		}
+		found = found || set[i] == c
+	}
+	return found != negate
+}
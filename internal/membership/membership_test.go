@@ -0,0 +1,102 @@
+package membership
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestMembership(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	m := New(client, "members", 30*time.Second)
+	ctx := context.Background()
+
+	for _, member := range []Member{
+		{ID: "server-2", Version: "1.1", Channels: []string{"broadcast.events"}},
+		{ID: "server-1", Version: "1.0", Patterns: []string{"broadcast.*"}, Metrics: map[string]int64{"processed": 3}},
+	} {
+		if err := m.Announce(ctx, member); err != nil {
+			t.Fatalf("unexpected announce error: %v", err)
+		}
+	}
+	members, err := m.Members(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(members) != 2 || members[0].ID != "server-1" || members[0].Metrics["processed"] != 3 || members[0].LastSeen.IsZero() {
+		t.Fatalf("unexpected members %+v", members)
+	}
+
+	if err := m.Leave(ctx, "server-1"); err != nil {
+		t.Fatalf("unexpected leave error: %v", err)
+	}
+	if members, _ := m.Members(ctx); len(members) != 1 || members[0].ID != "server-2" {
+		t.Fatalf("expected server-1 to have left, got %+v", members)
+	}
+
+	// a member that stops heartbeating expires
+	mr.FastForward(30 * time.Second)
+	if members, _ := m.Members(ctx); len(members) != 0 {
+		t.Fatalf("expected server-2 to expire, got %+v", members)
+	}
+	if n, _ := client.ZCard(ctx, "members").Result(); n != 0 {
+		t.Fatalf("expected expired members to be dropped from the index, %d left", n)
+	}
+}
+
+func TestMembershipRun(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	m := New(client, "members", time.Second)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	beats := 0
+	go func() {
+		defer close(done)
+		m.Run(ctx, 10*time.Millisecond, slog.New(slog.DiscardHandler), func() Member {
+			beats++
+			return Member{ID: "server-1", Metrics: map[string]int64{"beats": int64(beats)}}
+		})
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		members, _ := m.Members(context.Background())
+		if len(members) == 1 && members[0].Metrics["beats"] >= 3 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected repeated heartbeats, got %+v", members)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	cancel()
+	<-done
+	if members, _ := m.Members(context.Background()); len(members) != 0 {
+		t.Fatalf("expected the member to leave on shutdown, got %+v", members)
+	}
+}
+
+func TestListens(t *testing.T) {
+	m := Member{Channels: []string{"audit"}, Patterns: []string{"orders.*", "tenant:[a-c]?:events", `lit\*`}}
+	for channel, want := range map[string]bool{
+		"audit":            true,
+		"orders.created":   true,
+		"orders":           false,
+		"tenant:b1:events": true,
+		"tenant:d1:events": false,
+		"lit*":             true,
+		"litx":             false,
+	} {
+		if got := m.Listens(channel); got != want {
+			t.Errorf("Listens(%q) = %v, want %v", channel, got, want)
+		}
+	}
+}
@@ -0,0 +1,239 @@
+// Package metrics exposes processor, dispatcher and subscriber metrics in the
+// Prometheus text format.
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/dedup"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/dispatcher"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/events"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/processor"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/redisclient"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "broadcast"
+
+// Unrouted is the event_type label of events no handler is registered for.
+const Unrouted = "unrouted"
+
+// Metrics holds the collectors of one subscriber process. It implements
+// processor.Observer.
+type Metrics struct {
+	registry  *prometheus.Registry
+	processed *prometheus.CounterVec
+	failed    *prometheus.CounterVec
+	retried   *prometheus.CounterVec
+	dropped   *prometheus.CounterVec
+	deduped   *prometheus.CounterVec
+	latency   *prometheus.HistogramVec
+	lag       *prometheus.HistogramVec
+
+	compressionRatio *prometheus.HistogramVec
+	compressedBytes  *prometheus.CounterVec
+	originalBytes    *prometheus.CounterVec
+
+	route func(eventType string) (string, bool)
+}
+
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		processed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "events_processed_total",
+			Help:      "Events processed successfully.",
+		}, []string{"event_type"}),
+		failed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "events_failed_total",
+			Help:      "Events that still failed after their last retry.",
+		}, []string{"event_type"}),
+		retried: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "events_retried_total",
+			Help:      "Dispatch retries.",
+		}, []string{"event_type"}),
+		dropped: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "events_dropped_total",
+			Help:      "Events discarded because the processor queue was full.",
+		}, []string{"event_type", "policy"}),
+		deduped: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "events_deduplicated_total",
+			Help:      "Duplicate events skipped before dispatch, already handled (done) or still being handled (in-progress).",
+		}, []string{"event_type", "state"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "handler_duration_seconds",
+			Help:      "Time spent in a single handler call.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"event_type", "handler", "outcome"}),
+		lag: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "event_lag_seconds",
+			Help:      "Time from the event timestamp until processing finished.",
+			Buckets:   prometheus.ExponentialBuckets(0.005, 2, 14),
+		}, []string{"event_type"}),
+		compressionRatio: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "compression_ratio",
+			Help:      "Uncompressed size divided by compressed size of compressed messages.",
+			Buckets:   []float64{1, 1.5, 2, 3, 5, 10, 20, 50},
+		}, []string{"encoding"}),
+		compressedBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "compressed_bytes_total",
+			Help:      "Size of compressed messages on the wire.",
+		}, []string{"encoding"}),
+		originalBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "uncompressed_bytes_total",
+			Help:      "Size of compressed messages before compression.",
+		}, []string{"encoding"}),
+	}
+	m.registry.MustRegister(
+		m.processed, m.failed, m.retried, m.dropped, m.deduped, m.latency, m.lag,
+		m.compressionRatio, m.compressedBytes, m.originalBytes,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus text format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// WatchDispatcher labels events by the registration key d routes them to
+// instead of their type, and events d has no handler for as Unrouted, so
+// publishers cannot create a series per event type they make up. Call it
+// before events are processed.
+func (m *Metrics) WatchDispatcher(d *dispatcher.Dispatcher) {
+	m.route = d.Route
+}
+
+// eventType returns the event_type label of eventType.
+func (m *Metrics) eventType(eventType string) string {
+	if m.route == nil {
+		return eventType
+	}
+	if key, ok := m.route(eventType); ok {
+		return key
+	}
+	return Unrouted
+}
+
+func (m *Metrics) Processed(event events.Message, err error) {
+	eventType := m.eventType(event.Type)
+	if err != nil {
+		m.failed.WithLabelValues(eventType).Inc()
+	} else {
+		m.processed.WithLabelValues(eventType).Inc()
+	}
+	if !event.Timestamp.IsZero() {
+		m.lag.WithLabelValues(eventType).Observe(time.Since(event.Timestamp).Seconds())
+	}
+}
+
+func (m *Metrics) Retried(event events.Message, attempt int) {
+	m.retried.WithLabelValues(m.eventType(event.Type)).Inc()
+}
+
+func (m *Metrics) Dropped(event events.Message, policy processor.OverflowPolicy) {
+	m.dropped.WithLabelValues(m.eventType(event.Type), policy.String()).Inc()
+}
+
+func (m *Metrics) Deduplicated(event events.Message, state dedup.State) {
+	m.deduped.WithLabelValues(m.eventType(event.Type), state.String()).Inc()
+}
+
+// ObserveHandler records one handler call. It is meant for
+// dispatcher.Latency.
+func (m *Metrics) ObserveHandler(eventType, handler string, d time.Duration, err error) {
+	outcome := "success"
+	if err != nil {
+		outcome = "error"
+	}
+	m.latency.WithLabelValues(m.eventType(eventType), handler, outcome).Observe(d.Seconds())
+}
+
+// ObserveCompression records the sizes of one compressed message. It is a
+// codec.CompressionObserver.
+func (m *Metrics) ObserveCompression(encoding string, compressed, original int) {
+	if compressed <= 0 {
+		return
+	}
+	m.compressionRatio.WithLabelValues(encoding).Observe(float64(original) / float64(compressed))
+	m.compressedBytes.WithLabelValues(encoding).Add(float64(compressed))
+	m.originalBytes.WithLabelValues(encoding).Add(float64(original))
+}
+
+// WatchProcessor exposes the queue depth of p.
+func (m *Metrics) WatchProcessor(p *processor.Processor) {
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "queue_depth",
+		Help:      "Events waiting in the processor queue.",
+	}, func() float64 {
+		return float64(p.GetMetrics()["queued"])
+	}))
+}
+
+// WatchSubscriber exposes the connection state and reconnect count of s.
+func (m *Metrics) WatchSubscriber(s *redisclient.Subscriber) {
+	m.registry.MustRegister(
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "subscriber_disconnects_total",
+			Help:      "Times the subscription to Redis was lost.",
+		}, func() float64 {
+			return float64(s.Stats().Disconnects)
+		}),
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "subscriber_connected",
+			Help:      "1 while subscribed to Redis.",
+		}, func() float64 {
+			if s.Stats().Connected {
+				return 1
+			}
+			return 0
+		}),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "subscriber_outage_seconds_total",
+			Help:      "Total time spent reconnecting to Redis.",
+		}, func() float64 {
+			return s.Stats().TotalOutage.Seconds()
+		}),
+	)
+}
+
+// WatchVerifier exposes the messages v accepted and rejected.
+func (m *Metrics) WatchVerifier(v *redisclient.Verifier) {
+	m.registry.MustRegister(prometheus.NewCounterFunc(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_verified_total",
+		Help:      "Signed messages whose signature was valid.",
+	}, func() float64 {
+		return float64(v.Stats().Verified)
+	}))
+	for _, reason := range []string{"unsigned", "unknown_key", "bad_signature"} {
+		m.registry.MustRegister(prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace:   namespace,
+			Name:        "messages_rejected_total",
+			Help:        "Messages rejected before processing because of a missing or invalid signature.",
+			ConstLabels: prometheus.Labels{"reason": reason},
+		}, func() float64 {
+			return float64(v.Stats().Rejected[reason])
+		}))
+	}
+}
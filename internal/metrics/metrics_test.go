@@ -0,0 +1,167 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/codec"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/dedup"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/dispatcher"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/events"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/keyring"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/processor"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/redisclient"
+	"github.com/alicebob/miniredis/v2"
+)
+
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, err := io.ReadAll(rec.Body)
+	if err != nil {
+		t.Fatalf("failed to read metrics: %v", err)
+	}
+	return string(body)
+}
+
+func TestMetricsExposeEventCounters(t *testing.T) {
+	m := New()
+	event := events.Message{Type: "order.created", Timestamp: time.Now().Add(-time.Second)}
+
+	m.Processed(event, nil)
+	m.Processed(event, errors.New("boom"))
+	m.Retried(event, 1)
+	m.Dropped(event, processor.DropOldest)
+	m.Deduplicated(event, dedup.Done)
+	m.Deduplicated(event, dedup.InProgress)
+	m.ObserveHandler("order.created", "audit", 20*time.Millisecond, nil)
+	m.ObserveCompression("zstd", 100, 400)
+
+	body := scrape(t, m)
+	for _, want := range []string{
+		`broadcast_events_processed_total{event_type="order.created"} 1`,
+		`broadcast_events_failed_total{event_type="order.created"} 1`,
+		`broadcast_events_retried_total{event_type="order.created"} 1`,
+		`broadcast_events_dropped_total{event_type="order.created",policy="drop-oldest"} 1`,
+		`broadcast_events_deduplicated_total{event_type="order.created",state="done"} 1`,
+		`broadcast_events_deduplicated_total{event_type="order.created",state="in-progress"} 1`,
+		`broadcast_handler_duration_seconds_count{event_type="order.created",handler="audit",outcome="success"} 1`,
+		`broadcast_event_lag_seconds_count{event_type="order.created"} 2`,
+		`broadcast_compression_ratio_bucket{encoding="zstd",le="5"} 1`,
+		`broadcast_compressed_bytes_total{encoding="zstd"} 100`,
+		`broadcast_uncompressed_bytes_total{encoding="zstd"} 400`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected metrics to contain %q", want)
+		}
+	}
+}
+
+func TestMetricsObserveProcessor(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+	m := New()
+	d := dispatcher.New(logger)
+	d.Use(dispatcher.Latency(m.ObserveHandler))
+	d.Register("demo.message", dispatcher.HandlerFunc(func(ctx context.Context, event events.Message) error {
+		return nil
+	}), dispatcher.WithName("demo"))
+	p := processor.New(d, logger, 1, 10, processor.WithObserver(m))
+	m.WatchProcessor(p)
+
+	if err := p.Submit(events.Message{ID: "1", Type: "demo.message", Timestamp: time.Now()}); err != nil {
+		t.Fatalf("unexpected submit error: %v", err)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for p.GetMetrics()["processed"] == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	p.Stop()
+
+	body := scrape(t, m)
+	for _, want := range []string{
+		`broadcast_events_processed_total{event_type="demo.message"} 1`,
+		`broadcast_handler_duration_seconds_count{event_type="demo.message",handler="demo",outcome="success"} 1`,
+		`broadcast_queue_depth 0`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected metrics to contain %q", want)
+		}
+	}
+}
+
+func TestMetricsLabelEventsByRoute(t *testing.T) {
+	m := New()
+	d := dispatcher.New(slog.New(slog.DiscardHandler))
+	d.Register("orders.*", dispatcher.HandlerFunc(func(ctx context.Context, event events.Message) error {
+		return nil
+	}))
+	m.WatchDispatcher(d)
+
+	m.Processed(events.Message{Type: "orders.created"}, nil)
+	m.Processed(events.Message{Type: "orders.shipped"}, nil)
+	m.Processed(events.Message{Type: "made.up.1"}, nil)
+	m.Dropped(events.Message{Type: "made.up.2"}, processor.DropNewest)
+
+	body := scrape(t, m)
+	for _, want := range []string{
+		`broadcast_events_processed_total{event_type="orders.*"} 2`,
+		`broadcast_events_processed_total{event_type="unrouted"} 1`,
+		`broadcast_events_dropped_total{event_type="unrouted",policy="drop-newest"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected metrics to contain %q", want)
+		}
+	}
+	if strings.Contains(body, "made.up") {
+		t.Error("expected unregistered event types not to become labels")
+	}
+}
+
+func TestMetricsWatchSubscriber(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+	mr := miniredis.RunT(t)
+	client, err := redisclient.New(mr.Addr(), 0)
+	if err != nil {
+		t.Fatalf("Failed to create Redis client: %v", err)
+	}
+	p := processor.New(dispatcher.New(logger), logger, 1, 10)
+	defer p.Stop()
+
+	m := New()
+	m.WatchSubscriber(redisclient.NewSubscriber(client, "events", p, logger))
+
+	body := scrape(t, m)
+	for _, want := range []string{
+		"broadcast_subscriber_disconnects_total 0",
+		"broadcast_subscriber_connected 0",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected metrics to contain %q", want)
+		}
+	}
+}
+
+func TestMetricsWatchVerifier(t *testing.T) {
+	m := New()
+	v := redisclient.NewVerifier(keyring.New(), redisclient.SignatureRequired)
+	m.WatchVerifier(v)
+	_ = v.Verify(codec.Frame{ContentType: "application/json"}, "demo.message")
+
+	body := scrape(t, m)
+	for _, want := range []string{
+		`broadcast_messages_verified_total 0`,
+		`broadcast_messages_rejected_total{reason="unsigned"} 1`,
+		`broadcast_messages_rejected_total{reason="bad_signature"} 0`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected metrics to contain %q", want)
+		}
+	}
+}
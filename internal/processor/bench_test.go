@@ -0,0 +1,59 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"testing"
+
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/dispatcher"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/events"
+)
+
+type benchPayload struct {
+	OrderID string    `json:"order_id"`
+	Total   float64   `json:"total"`
+	Lines   []float64 `json:"lines"`
+}
+
+// BenchmarkTypedThroughput pushes events through the processor to a typed
+// handler, once with the payload kept raw as the subscriber now delivers it
+// and once with the generic map the subscriber used to decode.
+func BenchmarkTypedThroughput(b *testing.B) {
+	raw := json.RawMessage(`{"order_id":"o-1","total":99.5,"lines":[1,2,3,4,5,6,7,8,9,10]}`)
+	var generic map[string]any
+	if err := json.Unmarshal(raw, &generic); err != nil {
+		b.Fatal(err)
+	}
+
+	for _, bc := range []struct {
+		name    string
+		payload any
+	}{
+		{"raw", raw},
+		{"generic", generic},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			logger := slog.New(slog.DiscardHandler)
+			d := dispatcher.New(logger)
+			var wg sync.WaitGroup
+			dispatcher.RegisterTyped(d, "order.created", func(ctx context.Context, env events.Envelope[benchPayload]) error {
+				wg.Done()
+				return nil
+			})
+			p := New(d, logger, 4, 1024, WithOverflowPolicy(Block))
+			defer p.Stop()
+
+			event := events.Message{ID: "1", Type: "order.created", Payload: bc.payload}
+			b.ReportAllocs()
+			for b.Loop() {
+				wg.Add(1)
+				if err := p.Submit(event); err != nil {
+					b.Fatal(err)
+				}
+			}
+			wg.Wait()
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/dedup"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/events"
+)
+
+// ErrInProgress is passed to SubmitWithAck callbacks for a duplicate of an
+// event that is still being handled, here or on another node. It is not
+// handled, so at-least-once transports should redeliver it later.
+var ErrInProgress = errors.New("duplicate event still in progress")
+
+// WithDeduplication skips events whose ID store has already seen. A worker
+// claims the ID for processing and marks it done once the event has been
+// handled. A duplicate of a handled event counts as handled: SubmitWithAck
+// callbacks get nil. A duplicate of an event still in progress gets
+// ErrInProgress. Events without an ID are never deduplicated.
+func WithDeduplication(store dedup.Store) Option {
+	return func(p *Processor) { p.dedup = store }
+}
+
+// WithEventDeduplication overrides the store for a single event type. A nil
+// store turns deduplication off for it.
+func WithEventDeduplication(eventType string, store dedup.Store) Option {
+	return func(p *Processor) { p.dedupByType[eventType] = store }
+}
+
+// WithDedupClaimTTL sets how long a processing claim lasts, one minute by
+// default. It should exceed the time an event takes to handle, retries
+// included; the claim of a node that crashed expires after it.
+func WithDedupClaimTTL(d time.Duration) Option {
+	return func(p *Processor) { p.dedupClaimTTL = d }
+}
+
+func (p *Processor) dedupStore(eventType string) dedup.Store {
+	if store, ok := p.dedupByType[eventType]; ok {
+		return store
+	}
+	return p.dedup
+}
+
+// claim takes the processing claim on the ID of event. It returns
+// dedup.Claimed with the claim's token, empty when there is nothing to
+// settle, if the event should be handled. When the store fails the event is
+// handled, as losing an event is worse than handling it twice.
+func (p *Processor) claim(event events.Message) (string, dedup.State) {
+	store := p.dedupStore(event.Type)
+	if store == nil || event.ID == "" {
+		return "", dedup.Claimed
+	}
+	// the claim is made even while stopping, so draining still deduplicates
+	token, state, err := store.Claim(context.Background(), event.ID, p.dedupClaimTTL)
+	if err != nil {
+		p.logger.Error("failed to check for duplicate, handling event", "event_id", event.ID, "error", err)
+		return "", dedup.Claimed
+	}
+	switch state {
+	case dedup.Done:
+		p.duplicates.Add(1)
+		p.observer.Deduplicated(event, state)
+		p.logger.Info("duplicate event skipped", "event_id", event.ID, "event_type", event.Type)
+	case dedup.InProgress:
+		p.duplicates.Add(1)
+		p.observer.Deduplicated(event, state)
+		p.logger.Info("duplicate event skipped, still in progress", "event_id", event.ID, "event_type", event.Type)
+	}
+	return token, state
+}
+
+// settle marks the claimed event done once handled, or releases the claim
+// so a redelivery is handled again. A dead-lettered event is released too, so
+// it can be requeued from the dead-letter queue under the same ID.
+func (p *Processor) settle(event events.Message, token string, err error) {
+	store := p.dedupStore(event.Type)
+	if store == nil || token == "" {
+		return
+	}
+	ctx := context.Background()
+	if err == nil {
+		if err := store.Complete(ctx, event.ID, token); err != nil {
+			p.logger.Error("failed to mark event done", "event_id", event.ID, "error", err)
+		}
+		return
+	}
+	if err := store.Release(ctx, event.ID, token); err != nil {
+		p.logger.Error("failed to release duplicate claim", "event_id", event.ID, "error", err)
+	}
+}
@@ -0,0 +1,163 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/dedup"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/dispatcher"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/dlq"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/events"
+)
+
+func TestDeduplication(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	d := dispatcher.New(logger)
+	handled := make(chan string, 10)
+	record := dispatcher.HandlerFunc(func(ctx context.Context, event events.Message) error {
+		handled <- event.Type + "/" + event.ID
+		return nil
+	})
+	d.Register("order.created", record)
+	d.Register("metrics.sampled", record)
+
+	p := New(d, logger, 1, 10,
+		WithDeduplication(dedup.NewMemory(100, time.Minute)),
+		WithEventDeduplication("metrics.sampled", nil),
+	)
+	results := make(chan error, 10)
+	for _, event := range []events.Message{
+		{ID: "1", Type: "order.created"},
+		{ID: "1", Type: "order.created"},
+		{ID: "2", Type: "metrics.sampled"},
+		{ID: "2", Type: "metrics.sampled"},
+	} {
+		if err := p.SubmitWithAck(event, func(err error) { results <- err }); err != nil {
+			t.Fatalf("unexpected submit error: %v", err)
+		}
+	}
+	for range 4 {
+		if err := <-results; err != nil {
+			t.Fatalf("expected every event, duplicates included, to ack with nil, got %v", err)
+		}
+	}
+	p.Stop()
+
+	close(handled)
+	var got []string
+	for h := range handled {
+		got = append(got, h)
+	}
+	if len(got) != 3 || got[0] != "order.created/1" || got[1] != "metrics.sampled/2" {
+		t.Fatalf("expected the duplicate order to be skipped, handled %v", got)
+	}
+	if n := p.GetMetrics()["deduplicated"]; n != 1 {
+		t.Fatalf("expected 1 deduplicated event, got %d", n)
+	}
+}
+
+func TestDeduplicationReleasesFailedEvents(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	d := dispatcher.New(logger)
+	h := &fakeHandler{failures: 1}
+	d.Register("test", h)
+
+	p := New(d, logger, 1, 10,
+		WithRetryPolicy(ConstantRetry{Interval: time.Millisecond, Retries: 0}),
+		WithDeduplication(dedup.NewMemory(100, time.Minute)),
+	)
+	defer p.Stop()
+
+	results := make(chan error, 2)
+	for range 2 {
+		if err := p.SubmitWithAck(events.Message{ID: "1", Type: "test"}, func(err error) { results <- err }); err != nil {
+			t.Fatalf("unexpected submit error: %v", err)
+		}
+		select {
+		case <-results:
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for ack")
+		}
+	}
+	if h.calls.Load() != 2 {
+		t.Fatalf("expected the redelivery of a failed event to be handled, got %d calls", h.calls.Load())
+	}
+}
+
+func TestDeduplicationInProgress(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	d := dispatcher.New(logger)
+	h := &fakeHandler{}
+	d.Register("test", h)
+
+	store := dedup.NewMemory(100, time.Minute)
+	p := New(d, logger, 1, 10, WithDeduplication(store))
+	defer p.Stop()
+
+	// another node claimed the event and has not settled it yet
+	if _, _, err := store.Claim(context.Background(), "1", 50*time.Millisecond); err != nil {
+		t.Fatalf("unexpected claim error: %v", err)
+	}
+	submit := func() error {
+		t.Helper()
+		result := make(chan error, 1)
+		if err := p.SubmitWithAck(events.Message{ID: "1", Type: "test"}, func(err error) { result <- err }); err != nil {
+			t.Fatalf("unexpected submit error: %v", err)
+		}
+		select {
+		case err := <-result:
+			return err
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for ack")
+			return nil
+		}
+	}
+
+	if err := submit(); !errors.Is(err, ErrInProgress) || h.calls.Load() != 0 {
+		t.Fatalf("expected an unhandled in-progress duplicate, got %v after %d calls", err, h.calls.Load())
+	}
+	// the claim of a crashed node expires
+	time.Sleep(60 * time.Millisecond)
+	if err := submit(); err != nil || h.calls.Load() != 1 {
+		t.Fatalf("expected the event to be handled, got %v after %d calls", err, h.calls.Load())
+	}
+	if err := submit(); err != nil || h.calls.Load() != 1 {
+		t.Fatalf("expected a handled duplicate to ack with nil, got %v after %d calls", err, h.calls.Load())
+	}
+	if n := p.GetMetrics()["deduplicated"]; n != 2 {
+		t.Fatalf("expected the in-progress and the handled duplicate to be counted, got %d", n)
+	}
+}
+
+func TestDeduplicationReleasesDeadLetteredEvents(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	d := dispatcher.New(logger)
+	h := &fakeHandler{failures: 1}
+	d.Register("test", h)
+
+	p := New(d, logger, 1, 10,
+		WithRetryPolicy(ConstantRetry{Interval: time.Millisecond, Retries: 0}),
+		WithDeadLetter(dlq.NewMemoryStore()),
+		WithDeduplication(dedup.NewMemory(100, time.Minute)),
+	)
+	defer p.Stop()
+
+	results := make(chan error, 2)
+	for range 2 {
+		if err := p.SubmitWithAck(events.Message{ID: "1", Type: "test"}, func(err error) { results <- err }); err != nil {
+			t.Fatalf("unexpected submit error: %v", err)
+		}
+		select {
+		case <-results:
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for ack")
+		}
+	}
+	if h.calls.Load() != 2 {
+		t.Fatalf("expected a requeued dead-lettered event to be handled, got %d calls", h.calls.Load())
+	}
+}
@@ -0,0 +1,33 @@
+package processor
+
+import (
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/dedup"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/events"
+)
+
+// Observer is told what happens to each event, for metrics. Methods are called
+// from worker and submitting goroutines and must not block.
+type Observer interface {
+	// Processed is called once per event after its last attempt, with the
+	// final error or nil.
+	Processed(event events.Message, err error)
+	// Retried is called before every retry; attempt counts from 1.
+	Retried(event events.Message, attempt int)
+	// Dropped is called when the overflow policy discards an event.
+	Dropped(event events.Message, policy OverflowPolicy)
+	// Deduplicated is called when an event is skipped as a duplicate, with
+	// dedup.Done or dedup.InProgress.
+	Deduplicated(event events.Message, state dedup.State)
+}
+
+// WithObserver reports processing outcomes to o.
+func WithObserver(o Observer) Option {
+	return func(p *Processor) { p.observer = o }
+}
+
+type nopObserver struct{}
+
+func (nopObserver) Processed(events.Message, error)          {}
+func (nopObserver) Retried(events.Message, int)              {}
+func (nopObserver) Dropped(events.Message, OverflowPolicy)   {}
+func (nopObserver) Deduplicated(events.Message, dedup.State) {}
@@ -0,0 +1,218 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/events"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/tracing"
+)
+
+// ErrSpilled is passed to the done callback of SubmitWithAck when the queue
+// was full and the event was moved to the spill store. The event is processed
+// later, but without a callback.
+var ErrSpilled = errors.New("event spilled")
+
+// OverflowPolicy decides what Submit does when the queue is full.
+type OverflowPolicy int
+
+const (
+	// DropNewest rejects the submitted event with ErrQueueFull.
+	DropNewest OverflowPolicy = iota
+	// DropOldest discards the event that has waited longest to make room.
+	DropOldest
+	// Block waits for room until the submit context is done, or for the
+	// block timeout when it has no deadline.
+	Block
+	// Spill moves the event to the Spiller set with WithSpiller. Spilled
+	// events are fed back into the queue as room frees up, after events
+	// submitted since, so Spill does not keep the order of WithPartitionKey.
+	Spill
+)
+
+func (p OverflowPolicy) String() string {
+	switch p {
+	case DropNewest:
+		return "drop-newest"
+	case DropOldest:
+		return "drop-oldest"
+	case Block:
+		return "block"
+	case Spill:
+		return "spill"
+	default:
+		return fmt.Sprintf("OverflowPolicy(%d)", int(p))
+	}
+}
+
+// Spiller stores events the queue has no room for.
+type Spiller interface {
+	Push(ctx context.Context, event events.Message) error
+	// PushFront returns an event that was popped but not queued, so it is
+	// popped again before newer events.
+	PushFront(ctx context.Context, event events.Message) error
+	// Pop removes the oldest spilled event. ok is false when there is none.
+	Pop(ctx context.Context) (event events.Message, ok bool, err error)
+}
+
+// WithOverflowPolicy sets what happens when the queue is full. The default is
+// DropNewest.
+func WithOverflowPolicy(policy OverflowPolicy) Option {
+	return func(p *Processor) { p.overflow = policy }
+}
+
+// WithBlockTimeout limits how long the Block policy waits when the submit
+// context has no deadline. Zero waits until the processor stops.
+func WithBlockTimeout(d time.Duration) Option {
+	return func(p *Processor) { p.blockTimeout = d }
+}
+
+// WithSpiller sets the store used by the Spill policy. Without one, Spill
+// behaves like DropNewest.
+func WithSpiller(s Spiller) Option {
+	return func(p *Processor) { p.spiller = s }
+}
+
+// overflowStats counts the events each policy affected.
+type overflowStats struct {
+	droppedNewest atomic.Int64
+	droppedOldest atomic.Int64
+	blocked       atomic.Int64
+	blockTimeouts atomic.Int64
+	spilled       atomic.Int64
+	unspilled     atomic.Int64
+}
+
+func (s *overflowStats) metrics(m map[string]int64) {
+	m["overflow.dropped_newest"] = s.droppedNewest.Load()
+	m["overflow.dropped_oldest"] = s.droppedOldest.Load()
+	m["overflow.blocked"] = s.blocked.Load()
+	m["overflow.block_timeouts"] = s.blockTimeouts.Load()
+	m["overflow.spilled"] = s.spilled.Load()
+	m["overflow.unspilled"] = s.unspilled.Load()
+}
+
+// handleOverflow is called with the read lock held when queue is full.
+func (p *Processor) handleOverflow(ctx context.Context, queue chan task, t task) error {
+	switch p.overflow {
+	case Block:
+		return p.block(ctx, queue, t)
+	case DropOldest:
+		for {
+			select {
+			case queue <- t:
+				return nil
+			default:
+			}
+			select {
+			case old := <-queue:
+				tracing.End(old.queued, ErrQueueFull)
+				p.drop(old.event, "dropped oldest message, queue full")
+				p.overflowStats.droppedOldest.Add(1)
+				if old.done != nil {
+					old.done(ErrQueueFull)
+				}
+			default:
+			}
+		}
+	case Spill:
+		if p.spiller != nil {
+			return p.spill(t)
+		}
+	}
+	p.drop(t.event, "message dropped, queue full")
+	p.overflowStats.droppedNewest.Add(1)
+	return ErrQueueFull
+}
+
+func (p *Processor) block(ctx context.Context, queue chan task, t task) error {
+	p.overflowStats.blocked.Add(1)
+	if _, ok := ctx.Deadline(); !ok && p.blockTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.blockTimeout)
+		defer cancel()
+	}
+	select {
+	case queue <- t:
+		return nil
+	case <-p.ctx.Done():
+		return p.ctx.Err()
+	case <-ctx.Done():
+		p.overflowStats.blockTimeouts.Add(1)
+		p.drop(t.event, "message dropped, timed out waiting for queue")
+		return fmt.Errorf("%w: %w", ErrQueueFull, ctx.Err())
+	}
+}
+
+func (p *Processor) spill(t task) error {
+	// the spill store is written even while the caller's context is ending
+	if err := p.spiller.Push(context.Background(), t.event); err != nil {
+		p.logger.Error("failed to spill event", "event_id", t.event.ID, "error", err)
+		p.drop(t.event, "message dropped, queue full")
+		p.overflowStats.droppedNewest.Add(1)
+		return fmt.Errorf("%w: %w", ErrQueueFull, err)
+	}
+	p.overflowStats.spilled.Add(1)
+	t.queued.AddEvent("spilled")
+	t.queued.End()
+	p.logger.Warn("message spilled, queue full", "event_id", t.event.ID, "total_spilled", p.overflowStats.spilled.Load())
+	if t.done != nil {
+		t.done(ErrSpilled)
+	}
+	return nil
+}
+
+func (p *Processor) drop(event events.Message, msg string) {
+	p.dropped.Add(1)
+	p.observer.Dropped(event, p.overflow)
+	p.logger.Warn(msg, "event_id", event.ID, "total_dropped", p.dropped.Load())
+}
+
+// unspill feeds spilled events back into the queue until the processor stops.
+// An event popped while stopping is pushed back for the next run.
+func (p *Processor) unspill(interval time.Duration) {
+	defer p.wg.Done()
+	for {
+		event, ok, err := p.spiller.Pop(p.ctx)
+		if err != nil && p.ctx.Err() == nil {
+			p.logger.Error("failed to read spilled event", "error", err)
+		}
+		if err != nil || !ok {
+			select {
+			case <-p.ctx.Done():
+				return
+			case <-time.After(interval):
+			}
+			continue
+		}
+
+		if !p.requeue(task{event: event}) {
+			if err := p.spiller.PushFront(context.Background(), event); err != nil {
+				p.logger.Error("failed to return spilled event", "event_id", event.ID, "error", err)
+			}
+			return
+		}
+		p.overflowStats.unspilled.Add(1)
+	}
+}
+
+// requeue waits for room for t and reports whether it was queued before the
+// processor stopped.
+func (p *Processor) requeue(t task) bool {
+	p.closing.RLock()
+	defer p.closing.RUnlock()
+	if p.ctx.Err() != nil {
+		return false
+	}
+	t.queued = startQueueSpan(t.event)
+	select {
+	case p.queueFor(t.event) <- t:
+		return true
+	case <-p.ctx.Done():
+		tracing.End(t.queued, p.ctx.Err())
+		return false
+	}
+}
@@ -0,0 +1,263 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/dispatcher"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/events"
+)
+
+// gateHandler blocks every event until release is closed and records the IDs
+// it handled.
+type gateHandler struct {
+	started chan struct{}
+	release chan struct{}
+
+	mu  sync.Mutex
+	ids []string
+}
+
+func newGateHandler() *gateHandler {
+	return &gateHandler{started: make(chan struct{}, 100), release: make(chan struct{})}
+}
+
+func (h *gateHandler) Handle(ctx context.Context, event events.Message) error {
+	h.started <- struct{}{}
+	<-h.release
+	h.mu.Lock()
+	h.ids = append(h.ids, event.ID)
+	h.mu.Unlock()
+	return nil
+}
+
+func (h *gateHandler) handled() []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]string(nil), h.ids...)
+}
+
+// newBusyProcessor returns a processor with a single worker that is stuck on
+// event "0", so the next submits only fill the queue.
+func newBusyProcessor(t *testing.T, opts ...Option) (*Processor, *gateHandler) {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	d := dispatcher.New(logger)
+	h := newGateHandler()
+	d.Register("test", h)
+
+	p := New(d, logger, 1, 2, opts...)
+	if err := p.Submit(events.Message{ID: "0", Type: "test"}); err != nil {
+		t.Fatalf("unexpected submit error: %v", err)
+	}
+	<-h.started
+	return p, h
+}
+
+func waitForHandled(t *testing.T, h *gateHandler, n int) []string {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if ids := h.handled(); len(ids) >= n {
+			return ids
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("expected %d events to be handled, got %v", n, h.handled())
+	return nil
+}
+
+func TestOverflowDropNewest(t *testing.T) {
+	p, h := newBusyProcessor(t)
+	defer p.Stop()
+
+	for _, id := range []string{"1", "2"} {
+		if err := p.Submit(events.Message{ID: id, Type: "test"}); err != nil {
+			t.Fatalf("unexpected submit error: %v", err)
+		}
+	}
+	if err := p.Submit(events.Message{ID: "3", Type: "test"}); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("expected ErrQueueFull, got %v", err)
+	}
+	close(h.release)
+
+	if ids := waitForHandled(t, h, 3); ids[1] != "1" || ids[2] != "2" {
+		t.Fatalf("expected the newest event to be dropped, got %v", ids)
+	}
+	if n := p.GetMetrics()["overflow.dropped_newest"]; n != 1 {
+		t.Fatalf("expected 1 dropped_newest, got %d", n)
+	}
+}
+
+func TestOverflowDropOldest(t *testing.T) {
+	p, h := newBusyProcessor(t, WithOverflowPolicy(DropOldest))
+	defer p.Stop()
+
+	var dropped error
+	if err := p.SubmitWithAck(events.Message{ID: "1", Type: "test"}, func(err error) { dropped = err }); err != nil {
+		t.Fatalf("unexpected submit error: %v", err)
+	}
+	for _, id := range []string{"2", "3"} {
+		if err := p.Submit(events.Message{ID: id, Type: "test"}); err != nil {
+			t.Fatalf("unexpected submit error: %v", err)
+		}
+	}
+	if !errors.Is(dropped, ErrQueueFull) {
+		t.Fatalf("expected the dropped event to be acked with ErrQueueFull, got %v", dropped)
+	}
+	close(h.release)
+
+	if ids := waitForHandled(t, h, 3); ids[1] != "2" || ids[2] != "3" {
+		t.Fatalf("expected the oldest event to be dropped, got %v", ids)
+	}
+	metrics := p.GetMetrics()
+	if metrics["overflow.dropped_oldest"] != 1 || metrics["dropped"] != 1 {
+		t.Fatalf("expected 1 dropped_oldest, got %v", metrics)
+	}
+}
+
+func TestOverflowBlock(t *testing.T) {
+	p, h := newBusyProcessor(t, WithOverflowPolicy(Block))
+	defer p.Stop()
+
+	for _, id := range []string{"1", "2"} {
+		if err := p.Submit(events.Message{ID: id, Type: "test"}); err != nil {
+			t.Fatalf("unexpected submit error: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := p.SubmitContext(ctx, events.Message{ID: "timeout", Type: "test"}); !errors.Is(err, ErrQueueFull) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected ErrQueueFull after the deadline, got %v", err)
+	}
+
+	submitted := make(chan error, 1)
+	go func() {
+		submitted <- p.SubmitContext(context.Background(), events.Message{ID: "3", Type: "test"})
+	}()
+	select {
+	case err := <-submitted:
+		t.Fatalf("expected submit to block, returned %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(h.release)
+	if err := <-submitted; err != nil {
+		t.Fatalf("unexpected submit error: %v", err)
+	}
+
+	waitForHandled(t, h, 4)
+	metrics := p.GetMetrics()
+	if metrics["overflow.blocked"] != 2 || metrics["overflow.block_timeouts"] != 1 {
+		t.Fatalf("unexpected overflow metrics: %v", metrics)
+	}
+}
+
+func TestOverflowBlockTimeout(t *testing.T) {
+	p, h := newBusyProcessor(t, WithOverflowPolicy(Block), WithBlockTimeout(10*time.Millisecond))
+	defer func() {
+		close(h.release)
+		p.Stop()
+	}()
+
+	for _, id := range []string{"1", "2"} {
+		if err := p.Submit(events.Message{ID: id, Type: "test"}); err != nil {
+			t.Fatalf("unexpected submit error: %v", err)
+		}
+	}
+	if err := p.Submit(events.Message{ID: "3", Type: "test"}); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("expected ErrQueueFull after the block timeout, got %v", err)
+	}
+}
+
+func TestOverflowBlockUnblocksOnStop(t *testing.T) {
+	p, h := newBusyProcessor(t, WithOverflowPolicy(Block))
+	for _, id := range []string{"1", "2"} {
+		if err := p.Submit(events.Message{ID: id, Type: "test"}); err != nil {
+			t.Fatalf("unexpected submit error: %v", err)
+		}
+	}
+
+	submitted := make(chan error, 1)
+	go func() {
+		submitted <- p.Submit(events.Message{ID: "3", Type: "test"})
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(h.release)
+	p.Stop()
+
+	select {
+	case <-submitted:
+	case <-time.After(time.Second):
+		t.Fatal("expected a blocked submit to return once the processor stopped")
+	}
+}
+
+type memorySpiller struct {
+	mu     sync.Mutex
+	events []events.Message
+}
+
+func (s *memorySpiller) Push(ctx context.Context, event events.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, event)
+	return nil
+}
+
+func (s *memorySpiller) PushFront(ctx context.Context, event events.Message) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append([]events.Message{event}, s.events...)
+	return nil
+}
+
+func (s *memorySpiller) Pop(ctx context.Context) (events.Message, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.events) == 0 {
+		return events.Message{}, false, nil
+	}
+	event := s.events[0]
+	s.events = s.events[1:]
+	return event, true, nil
+}
+
+func TestOverflowSpill(t *testing.T) {
+	spiller := &memorySpiller{}
+	p, h := newBusyProcessor(t, WithOverflowPolicy(Spill), WithSpiller(spiller))
+	defer p.Stop()
+
+	for _, id := range []string{"1", "2"} {
+		if err := p.Submit(events.Message{ID: id, Type: "test"}); err != nil {
+			t.Fatalf("unexpected submit error: %v", err)
+		}
+	}
+	var acked error
+	if err := p.SubmitWithAck(events.Message{ID: "3", Type: "test"}, func(err error) { acked = err }); err != nil {
+		t.Fatalf("expected the event to be spilled, got %v", err)
+	}
+	if !errors.Is(acked, ErrSpilled) {
+		t.Fatalf("expected ErrSpilled, got %v", acked)
+	}
+	close(h.release)
+
+	if ids := waitForHandled(t, h, 4); ids[3] != "3" {
+		t.Fatalf("expected the spilled event to be processed last, got %v", ids)
+	}
+	metrics := p.GetMetrics()
+	if metrics["overflow.spilled"] != 1 || metrics["overflow.unspilled"] != 1 || metrics["dropped"] != 0 {
+		t.Fatalf("unexpected overflow metrics: %v", metrics)
+	}
+}
+
+func TestOverflowPolicyString(t *testing.T) {
+	if Spill.String() != "spill" || DropOldest.String() != "drop-oldest" {
+		t.Fatalf("unexpected policy names %q, %q", Spill, DropOldest)
+	}
+}
@@ -0,0 +1,103 @@
+package processor
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"sync/atomic"
+	"time"
+
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/events"
+)
+
+// PartitionKey extracts the ordering key of an event. Events with the same
+// non-empty key are processed one at a time, in submission order.
+type PartitionKey func(events.Message) string
+
+// WithPartitionKey gives every worker its own lane and routes events to a lane
+// by key hash, so events for the same key are handled in order while keys on
+// different lanes still run in parallel. Events without a key are spread
+// round-robin. Under the Spill overflow policy a spilled event can be
+// handled after a later one with the same key.
+func WithPartitionKey(key PartitionKey) Option {
+	return func(p *Processor) { p.partitionKey = key }
+}
+
+// MessageKey uses the Key field of the event envelope.
+func MessageKey(event events.Message) string {
+	return event.Key
+}
+
+// PayloadField uses a top-level field of an object payload, such as
+// "order_id".
+func PayloadField(name string) PartitionKey {
+	return func(event events.Message) string {
+		var v any
+		switch payload := event.Payload.(type) {
+		case map[string]any:
+			v = payload[name]
+		case json.RawMessage:
+			// only the field itself is decoded
+			var fields map[string]json.RawMessage
+			if json.Unmarshal(payload, &fields) != nil {
+				return ""
+			}
+			raw, ok := fields[name]
+			if !ok || json.Unmarshal(raw, &v) != nil {
+				return ""
+			}
+		case events.PayloadDecoder:
+			// the raw payload of a binary codec
+			var fields map[string]any
+			if payload.DecodePayload(&fields) != nil {
+				return ""
+			}
+			v = fields[name]
+		}
+		if v == nil {
+			return ""
+		}
+		return fmt.Sprint(v)
+	}
+}
+
+type lane struct {
+	queue     chan task
+	processed atomic.Int64
+	busy      atomic.Int64
+}
+
+// LaneStats describes the load on one worker lane.
+type LaneStats struct {
+	Queued    int
+	Processed int64
+	Busy      time.Duration
+}
+
+// LaneStats returns per-lane statistics, or nil when events are not
+// partitioned.
+func (p *Processor) LaneStats() []LaneStats {
+	if p.lanes == nil {
+		return nil
+	}
+	stats := make([]LaneStats, len(p.lanes))
+	for i, l := range p.lanes {
+		stats[i] = LaneStats{
+			Queued:    len(l.queue),
+			Processed: l.processed.Load(),
+			Busy:      time.Duration(l.busy.Load()),
+		}
+	}
+	return stats
+}
+
+func (p *Processor) laneFor(event events.Message) *lane {
+	key := p.partitionKey(event)
+	if key == "" {
+		n := p.nextLane.Add(1)
+		return p.lanes[n%uint64(len(p.lanes))]
+	}
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	return p.lanes[h.Sum64()%uint64(len(p.lanes))]
+}
@@ -0,0 +1,165 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/codec"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/dispatcher"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/events"
+)
+
+type orderRecorder struct {
+	mu    sync.Mutex
+	seen  map[string][]int
+	total int
+}
+
+func (r *orderRecorder) Handle(ctx context.Context, event events.Message) error {
+	time.Sleep(time.Duration(rand.IntN(3)) * time.Millisecond)
+	payload := event.Payload.(map[string]any)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seen[event.Key] = append(r.seen[event.Key], payload["seq"].(int))
+	r.total++
+	return nil
+}
+
+func TestPartitionedEventsStayInOrder(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	d := dispatcher.New(logger)
+	rec := &orderRecorder{seen: make(map[string][]int)}
+	d.Register("test", rec)
+
+	p := New(d, logger, 4, 400, WithPartitionKey(MessageKey))
+
+	keys := []string{"a", "b", "c", "d", "e"}
+	for seq := 0; seq < 40; seq++ {
+		for _, key := range keys {
+			event := events.Message{ID: fmt.Sprintf("%s-%d", key, seq), Type: "test", Key: key, Payload: map[string]any{"seq": seq}}
+			if err := p.Submit(event); err != nil {
+				t.Fatalf("unexpected submit error: %v", err)
+			}
+		}
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		rec.mu.Lock()
+		total := rec.total
+		rec.mu.Unlock()
+		if total == 200 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	p.Stop()
+
+	for _, key := range keys {
+		seqs := rec.seen[key]
+		if len(seqs) != 40 {
+			t.Fatalf("key %s: expected 40 events, got %d", key, len(seqs))
+		}
+		for i, seq := range seqs {
+			if seq != i {
+				t.Fatalf("key %s: events out of order: %v", key, seqs)
+			}
+		}
+	}
+
+	stats := p.LaneStats()
+	if len(stats) != 4 {
+		t.Fatalf("expected 4 lanes, got %d", len(stats))
+	}
+	var processed int64
+	for _, s := range stats {
+		processed += s.Processed
+	}
+	if processed != 200 {
+		t.Fatalf("expected lanes to have processed 200 events, got %d", processed)
+	}
+	metrics := p.GetMetrics()
+	if _, ok := metrics["lane.3.busy_ms"]; !ok {
+		t.Fatalf("expected per-lane metrics, got %v", metrics)
+	}
+}
+
+func TestSameKeyUsesSameLane(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	p := New(dispatcher.New(logger), logger, 8, 8, WithPartitionKey(PayloadField("order_id")))
+	defer p.Stop()
+
+	event := events.Message{Payload: map[string]any{"order_id": 42}}
+	first := p.laneFor(event)
+	for i := 0; i < 10; i++ {
+		if p.laneFor(event) != first {
+			t.Fatal("expected the same key to always map to the same lane")
+		}
+	}
+}
+
+func TestPayloadField(t *testing.T) {
+	key := PayloadField("order_id")
+
+	if got := key(events.Message{Payload: map[string]any{"order_id": "o-1"}}); got != "o-1" {
+		t.Errorf("expected o-1, got %q", got)
+	}
+	if got := key(events.Message{Payload: map[string]any{"order_id": float64(7)}}); got != "7" {
+		t.Errorf("expected 7, got %q", got)
+	}
+	if got := key(events.Message{Payload: map[string]any{}}); got != "" {
+		t.Errorf("expected empty key for missing field, got %q", got)
+	}
+	if got := key(events.Message{Payload: json.RawMessage(`{"order_id":"o-2","items":[1,2]}`)}); got != "o-2" {
+		t.Errorf("expected o-2 from a raw payload, got %q", got)
+	}
+	if got := key(events.Message{Payload: json.RawMessage(`"not an object"`)}); got != "" {
+		t.Errorf("expected empty key for non-object raw payload, got %q", got)
+	}
+	if got := key(events.Message{Payload: "not a map"}); got != "" {
+		t.Errorf("expected empty key for non-map payload, got %q", got)
+	}
+}
+
+func TestPayloadFieldOfBinaryCodecs(t *testing.T) {
+	key := PayloadField("order_id")
+	for _, name := range []string{"json", "msgpack", "cbor", "protobuf"} {
+		t.Run(name, func(t *testing.T) {
+			c, _ := codec.Named(name)
+			data, err := codec.Encode(c, events.Message{ID: "1", Type: "order.created", Payload: map[string]any{"order_id": "o-1", "total": 7}})
+			if err != nil {
+				t.Fatalf("unexpected encode error: %v", err)
+			}
+			var event events.Message
+			if err := codec.Decode(data, &event); err != nil {
+				t.Fatalf("unexpected decode error: %v", err)
+			}
+			if got := key(event); got != "o-1" {
+				t.Fatalf("expected o-1, got %q", got)
+			}
+			if got := PayloadField("total")(event); got != "7" {
+				t.Fatalf("expected 7, got %q", got)
+			}
+			if got := PayloadField("missing")(event); got != "" {
+				t.Fatalf("expected empty key for missing field, got %q", got)
+			}
+		})
+	}
+}
+
+func TestUnpartitionedProcessorHasNoLanes(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	p := New(dispatcher.New(logger), logger, 2, 10)
+	defer p.Stop()
+
+	if p.LaneStats() != nil {
+		t.Fatal("expected no lane stats without a partition key")
+	}
+}
@@ -3,52 +3,156 @@ package processor
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
+	"slices"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/dedup"
 	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/dispatcher"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/dlq"
 	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/events"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var (
 	ErrQueueFull = errors.New("processor queue is full")
+	// ErrDeadLettered wraps the dispatch error of an event that exhausted its
+	// retries and was stored in the dead-letter sink.
+	ErrDeadLettered = errors.New("event dead-lettered")
 )
 
+type task struct {
+	event  events.Message
+	done   func(error)
+	queued trace.Span // ends when a worker picks the task up
+}
+
 type Processor struct {
-	queue      chan events.Message
-	dispatcher *dispatcher.Dispatcher
-	logger     *slog.Logger
-	ctx        context.Context
-	cancel     context.CancelFunc
-	wg         sync.WaitGroup
-	processed  atomic.Int64
-	dropped    atomic.Int64
-	maxRetries int
-	retryDelay time.Duration
-}
-
-func New(dispatcher *dispatcher.Dispatcher, logger *slog.Logger, workers int, buffer int) *Processor {
+	queue       chan task
+	dispatcher  *dispatcher.Dispatcher
+	logger      *slog.Logger
+	ctx         context.Context
+	cancel      context.CancelFunc
+	wg          sync.WaitGroup
+	processed   atomic.Int64
+	dropped     atomic.Int64
+	retry       RetryPolicy
+	retryByType map[string]RetryPolicy
+	deadLetter  dlq.Sink
+	observer    Observer
+	dedup       dedup.Store
+	dedupByType map[string]dedup.Store
+	duplicates  atomic.Int64
+
+	dedupClaimTTL time.Duration
+
+	partitionKey PartitionKey
+	lanes        []*lane
+	nextLane     atomic.Uint64
+
+	overflow      OverflowPolicy
+	blockTimeout  time.Duration
+	spiller       Spiller
+	overflowStats overflowStats
+	// closing guards the queues against being closed by Stop while a submit
+	// is sending on them
+	closing sync.RWMutex
+}
+
+type Option func(*Processor)
+
+// WithRetryPolicy sets the retry policy used for all event types without an
+// override.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(p *Processor) { p.retry = policy }
+}
+
+// WithEventRetryPolicy overrides the retry policy for a single event type.
+func WithEventRetryPolicy(eventType string, policy RetryPolicy) Option {
+	return func(p *Processor) { p.retryByType[eventType] = policy }
+}
+
+// WithDeadLetter sends events that exhaust their retries to sink.
+func WithDeadLetter(sink dlq.Sink) Option {
+	return func(p *Processor) { p.deadLetter = sink }
+}
+
+func New(dispatcher *dispatcher.Dispatcher, logger *slog.Logger, workers int, buffer int, opts ...Option) *Processor {
 	ctx, cancel := context.WithCancel(context.Background())
 	p := &Processor{
-		queue:      make(chan events.Message, buffer),
-		dispatcher: dispatcher,
-		logger:     logger,
-		ctx:        ctx,
-		cancel:     cancel,
-		maxRetries: 3,
-		retryDelay: 100 * time.Millisecond,
+		queue:       make(chan task, buffer),
+		dispatcher:  dispatcher,
+		logger:      logger,
+		ctx:         ctx,
+		cancel:      cancel,
+		retry:       DefaultRetryPolicy(),
+		retryByType: make(map[string]RetryPolicy),
+		dedupByType: make(map[string]dedup.Store),
+		observer:    nopObserver{},
+
+		dedupClaimTTL: time.Minute,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+
+	if p.overflow == Spill && p.spiller != nil {
+		p.wg.Add(1)
+		go p.unspill(100 * time.Millisecond)
+	}
+
+	if p.partitionKey != nil {
+		// one worker per lane keeps events with the same key in order
+		laneBuffer := max(1, (buffer+workers-1)/workers)
+		for i := 0; i < workers; i++ {
+			l := &lane{queue: make(chan task, laneBuffer)}
+			p.lanes = append(p.lanes, l)
+			p.wg.Add(1)
+			go p.worker(i, l.queue, l)
+		}
+		return p
 	}
 
 	for i := 0; i < workers; i++ {
 		p.wg.Add(1)
-		go p.worker(i)
+		go p.worker(i, p.queue, nil)
 	}
 	return p
 }
 
 func (p *Processor) Submit(event events.Message) error {
+	return p.submit(context.Background(), task{event: event})
+}
+
+// SubmitContext enqueues event like Submit. Under the Block overflow policy it
+// waits for room until ctx is done.
+func (p *Processor) SubmitContext(ctx context.Context, event events.Message) error {
+	return p.submit(ctx, task{event: event})
+}
+
+// SubmitWithAck enqueues event like Submit and calls done once processing has
+// finished, with nil on success or the last dispatch error otherwise. done is
+// not called when the event is rejected.
+func (p *Processor) SubmitWithAck(event events.Message, done func(error)) error {
+	return p.submit(context.Background(), task{event: event, done: done})
+}
+
+// SubmitContextWithAck combines SubmitContext and SubmitWithAck.
+func (p *Processor) SubmitContextWithAck(ctx context.Context, event events.Message, done func(error)) error {
+	return p.submit(ctx, task{event: event, done: done})
+}
+
+func (p *Processor) submit(ctx context.Context, t task) error {
+	p.closing.RLock()
+	defer p.closing.RUnlock()
+
 	// if context has been cancelled we should not try to send on the channel
 	select {
 	case <-p.ctx.Done():
@@ -57,34 +161,70 @@ func (p *Processor) Submit(event events.Message) error {
 	default:
 	}
 
-	// try to enqueue without blocking; if the buffer is full we drop
+	// try to enqueue without blocking; if the buffer is full the overflow
+	// policy decides
+	t.queued = startQueueSpan(t.event)
+	queue := p.queueFor(t.event)
 	select {
-	case p.queue <- event:
+	case queue <- t:
 		return nil
 	default:
-		p.dropped.Add(1)
-		p.logger.Warn("message dropped, queue full", "event_id", event.ID, "total_dropped", p.dropped.Load())
-		return ErrQueueFull
+		err := p.handleOverflow(ctx, queue, t)
+		if err != nil {
+			tracing.End(t.queued, err)
+		}
+		return err
+	}
+}
+
+func startQueueSpan(event events.Message) trace.Span {
+	_, span := tracing.Tracer().Start(tracing.Extract(context.Background(), event), "queue", tracing.Attributes(event))
+	return span
+}
+
+func (p *Processor) queueFor(event events.Message) chan task {
+	if p.lanes != nil {
+		return p.laneFor(event).queue
 	}
+	return p.queue
 }
 
 func (p *Processor) Stop() {
 	p.logger.Info("processor stopping, draining queue", "processed", p.processed.Load(), "dropped", p.dropped.Load())
 	p.cancel()
+	p.closing.Lock()
 	close(p.queue)
+	for _, l := range p.lanes {
+		close(l.queue)
+	}
+	p.closing.Unlock()
 	p.wg.Wait()
 	p.logger.Info("processor stopped", "total_processed", p.processed.Load(), "total_dropped", p.dropped.Load())
 }
 
+// GetMetrics returns the processor counters, including deduplicated events
+// and overflow.* counts of the events each overflow policy affected. When
+// events are partitioned it also reports lane.<n>.queued, lane.<n>.processed
+// and lane.<n>.busy_ms for every lane.
 func (p *Processor) GetMetrics() map[string]int64 {
-	return map[string]int64{
-		"processed": p.processed.Load(),
-		"dropped":   p.dropped.Load(),
-		"queued":    int64(len(p.queue)),
+	queued := len(p.queue)
+	metrics := map[string]int64{
+		"processed":    p.processed.Load(),
+		"dropped":      p.dropped.Load(),
+		"deduplicated": p.duplicates.Load(),
+	}
+	for i, l := range p.LaneStats() {
+		queued += l.Queued
+		metrics[fmt.Sprintf("lane.%d.queued", i)] = int64(l.Queued)
+		metrics[fmt.Sprintf("lane.%d.processed", i)] = l.Processed
+		metrics[fmt.Sprintf("lane.%d.busy_ms", i)] = l.Busy.Milliseconds()
 	}
+	metrics["queued"] = int64(queued)
+	p.overflowStats.metrics(metrics)
+	return metrics
 }
 
-func (p *Processor) worker(id int) {
+func (p *Processor) worker(id int, queue <-chan task, l *lane) {
 	defer p.wg.Done()
 	p.logger.Info("worker started", "worker_id", id)
 	for {
@@ -92,34 +232,171 @@ func (p *Processor) worker(id int) {
 		case <-p.ctx.Done():
 			p.logger.Info("worker stopping", "worker_id", id)
 			return
-		case event, ok := <-p.queue:
+		case t, ok := <-queue:
 			if !ok {
 				p.logger.Info("worker queue closed", "worker_id", id)
 				return
 			}
-			p.processWithRetry(event)
+			t.queued.End()
+			token, state := p.claim(t.event)
+			if state != dedup.Claimed {
+				var err error
+				if state == dedup.InProgress {
+					err = ErrInProgress
+				}
+				if t.done != nil {
+					t.done(err)
+				}
+				continue
+			}
+			start := time.Now()
+			err := p.processWithRetry(tracing.Extract(p.ctx, t.event), t.event)
+			p.settle(t.event, token, err)
 			p.processed.Add(1)
+			p.observer.Processed(t.event, err)
+			if l != nil {
+				l.processed.Add(1)
+				l.busy.Add(int64(time.Since(start)))
+			}
+			if t.done != nil {
+				t.done(err)
+			}
 		}
 	}
 }
 
-func (p *Processor) processWithRetry(event events.Message) {
-	for attempt := 0; attempt <= p.maxRetries; attempt++ {
+func (p *Processor) retryPolicy(eventType string) RetryPolicy {
+	if policy, ok := p.retryByType[eventType]; ok {
+		return policy
+	}
+	return p.retry
+}
+
+// processWithRetry dispatches event and retries according to the event's
+// retry policy. When several handlers are registered, only the handlers that
+// failed with a retryable error (or were skipped) run again.
+func (p *Processor) processWithRetry(ctx context.Context, event events.Message) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "dispatch", tracing.Attributes(event))
+	defer func() { tracing.EndEvent(span, event, err) }()
+
+	policy := p.retryPolicy(event.Type)
+	maxRetries := policy.MaxRetries()
+
+	var pending []string // handlers to run on the next attempt, nil for all
+	failed := make(map[string]*dispatcher.HandlerError)
+	attempts := 0
+	gaveUp := false
+	for attempt := 0; attempt <= maxRetries; attempt++ {
 		if attempt > 0 {
 			select {
 			case <-p.ctx.Done():
-				return
-			case <-time.After(p.retryDelay * time.Duration(attempt)):
+				return p.ctx.Err()
+			case <-time.After(policy.Delay(attempt)):
 			}
+			p.observer.Retried(event, attempt)
 		}
 
-		err := p.dispatcher.Dispatch(p.ctx, event)
-		if err == nil {
-			return
+		attempts++
+		attemptCtx, attemptSpan := tracing.Tracer().Start(ctx, "attempt", trace.WithAttributes(attribute.Int("attempt", attempts)))
+		err = p.dispatcher.DispatchHandlers(attemptCtx, event, pending)
+		tracing.EndEvent(attemptSpan, event, err)
+		if pending == nil {
+			clear(failed)
+		}
+		for _, name := range pending {
+			delete(failed, name)
+		}
+
+		var dispatchErr *dispatcher.DispatchError
+		if errors.As(err, &dispatchErr) {
+			for _, f := range dispatchErr.Failures {
+				failed[f.Handler] = f
+			}
+			pending = retryableHandlers(dispatchErr)
+			if len(pending) == 0 {
+				gaveUp = true
+				break
+			}
+		} else if err == nil {
+			pending = nil
+			break
+		} else if dispatcher.IsPermanent(err) {
+			gaveUp = true
+			break
+		}
+		if attempt < maxRetries {
+			p.logger.Warn("dispatch failed, retrying", "event_id", event.ID, "error", event.Redact(err), "attempt", attempt+1, "max_retries", maxRetries, "handlers", pending)
+		}
+	}
+
+	if len(failed) > 0 {
+		err = collectFailures(event.Type, failed, pending)
+	}
+	if err == nil {
+		return nil
+	}
+	if gaveUp {
+		p.logger.Error("dispatch failed permanently, not retrying", "event_id", event.ID, "error", event.Redact(err), "attempts", attempts)
+	} else {
+		p.logger.Error("dispatch failed after retries", "event_id", event.ID, "max_retries", maxRetries)
+	}
+	if p.sendToDeadLetter(event, err, attempts) {
+		return fmt.Errorf("%w: %w", ErrDeadLettered, err)
+	}
+	return err
+}
+
+// retryableHandlers returns the handlers worth running again after err.
+func retryableHandlers(err *dispatcher.DispatchError) []string {
+	names := make([]string, 0, len(err.Failures)+len(err.Skipped))
+	for _, f := range err.Failures {
+		if !dispatcher.IsPermanent(f.Err) {
+			names = append(names, f.Handler)
 		}
-		if attempt < p.maxRetries {
-			p.logger.Warn("dispatch failed, retrying", "event_id", event.ID, "error", err, "attempt", attempt+1, "max_retries", p.maxRetries)
+	}
+	return append(names, err.Skipped...)
+}
+
+// collectFailures builds the final error from the handlers that were still
+// failing, or never ran, when retrying stopped.
+func collectFailures(eventType string, failed map[string]*dispatcher.HandlerError, pending []string) *dispatcher.DispatchError {
+	err := &dispatcher.DispatchError{EventType: eventType}
+	for _, f := range failed {
+		err.Failures = append(err.Failures, f)
+	}
+	slices.SortFunc(err.Failures, func(a, b *dispatcher.HandlerError) int {
+		return strings.Compare(a.Handler, b.Handler)
+	})
+	for _, name := range pending {
+		if _, ok := failed[name]; !ok {
+			err.Skipped = append(err.Skipped, name)
 		}
 	}
-	p.logger.Error("dispatch failed after retries", "event_id", event.ID, "max_retries", p.maxRetries)
+	return err
+}
+
+func (p *Processor) sendToDeadLetter(event events.Message, err error, attempts int) bool {
+	if p.deadLetter == nil {
+		return false
+	}
+	handlerType := p.dispatcher.HandlerType(event.Type)
+	var dispatchErr *dispatcher.DispatchError
+	if errors.As(err, &dispatchErr) {
+		handlerType = strings.Join(append(dispatchErr.Failed(), dispatchErr.Skipped...), ",")
+	}
+	record := dlq.Record{
+		Message:     event,
+		Channel:     event.Channel,
+		Error:       fmt.Sprint(event.Redact(err.Error())),
+		Attempts:    attempts,
+		HandlerType: handlerType,
+		FailedAt:    time.Now(),
+	}
+	// the processor context may already be cancelled while draining
+	if err := p.deadLetter.Put(context.Background(), record); err != nil {
+		p.logger.Error("failed to dead-letter event", "event_id", event.ID, "error", err)
+		return false
+	}
+	p.logger.Info("event dead-lettered", "event_id", event.ID, "event_type", event.Type)
+	return true
 }
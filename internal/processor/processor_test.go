@@ -5,11 +5,13 @@ import (
 	"errors"
 	"log/slog"
 	"os"
+	"strings"
 	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/dispatcher"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/dlq"
 	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/events"
 )
 
@@ -30,11 +32,11 @@ func TestNewProcessor(t *testing.T) {
 	if len(p.queue) != 0 {
 		t.Fatal("expected queue to be empty on initialization")
 	}
-	if p.maxRetries != 3 {
-		t.Fatalf("expected maxRetries to be 3, got %d", p.maxRetries)
+	if p.retry.MaxRetries() != 3 {
+		t.Fatalf("expected maxRetries to be 3, got %d", p.retry.MaxRetries())
 	}
-	if p.retryDelay != 100*time.Millisecond {
-		t.Fatalf("expected retryDelay to be 100ms, got %s", p.retryDelay)
+	if p.retry.Delay(1) != 100*time.Millisecond {
+		t.Fatalf("expected retryDelay to be 100ms, got %s", p.retry.Delay(1))
 	}
 
 }
@@ -218,3 +220,228 @@ func TestWorkerExceedsMaxRetries(t *testing.T) {
 		t.Fatalf("expected processed count to be 1 after exceeding retries, got %d", p.processed.Load())
 	}
 }
+
+func TestSubmitWithAckReportsResult(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	d := dispatcher.New(logger)
+
+	ok := &fakeHandler{failures: 0}
+	failing := &fakeHandler{failures: 10}
+	d.Register("ok", ok)
+	d.Register("failing", failing)
+
+	p := New(d, logger, 1, 2)
+
+	results := make(chan error, 2)
+	if err := p.SubmitWithAck(events.Message{ID: "1", Type: "ok"}, func(err error) { results <- err }); err != nil {
+		t.Fatalf("unexpected submit error: %v", err)
+	}
+	if err := p.SubmitWithAck(events.Message{ID: "2", Type: "failing"}, func(err error) { results <- err }); err != nil {
+		t.Fatalf("unexpected submit error: %v", err)
+	}
+
+	if err := <-results; err != nil {
+		t.Fatalf("expected successful event to ack with nil, got %v", err)
+	}
+	select {
+	case err := <-results:
+		if err == nil {
+			t.Fatal("expected failed event to ack with an error")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for failed event ack")
+	}
+	p.Stop()
+}
+
+func TestWorkerDeadLettersAfterMaxRetries(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	d := dispatcher.New(logger)
+
+	h := &fakeHandler{failures: 10}
+	d.Register("test", h)
+
+	store := dlq.NewMemoryStore()
+	p := New(d, logger, 1, 1, WithDeadLetter(store))
+
+	done := make(chan error, 1)
+	if err := p.SubmitWithAck(events.Message{ID: "4", Type: "test", Channel: "orders.paid"}, func(err error) { done <- err }); err != nil {
+		t.Fatalf("unexpected submit error: %v", err)
+	}
+	select {
+	case err := <-done:
+		if !errors.Is(err, ErrDeadLettered) {
+			t.Fatalf("expected ErrDeadLettered, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for event to fail")
+	}
+	p.Stop()
+
+	records, err := store.List(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("unexpected list error: %v", err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("expected 1 dead-letter record, got %d", len(records))
+	}
+	r := records[0]
+	if r.Message.ID != "4" {
+		t.Errorf("expected original message to be kept, got %+v", r.Message)
+	}
+	if r.Channel != "orders.paid" {
+		t.Errorf("expected the receiving channel to be kept, got %q", r.Channel)
+	}
+	if !strings.Contains(r.Error, "simulated failure") {
+		t.Errorf("expected last error to be recorded, got %q", r.Error)
+	}
+	if r.Attempts != 4 {
+		t.Errorf("expected 4 attempts, got %d", r.Attempts)
+	}
+	if r.HandlerType != "*processor.fakeHandler" {
+		t.Errorf("expected handler type *processor.fakeHandler, got %q", r.HandlerType)
+	}
+	if r.FailedAt.IsZero() {
+		t.Error("expected failure time to be set")
+	}
+}
+
+type permanentHandler struct {
+	calls atomic.Int32
+}
+
+func (h *permanentHandler) Handle(ctx context.Context, event events.Message) error {
+	h.calls.Add(1)
+	return dispatcher.Permanent(errors.New("invalid payload"))
+}
+
+func TestWorkerSkipsRetriesOnPermanentError(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	d := dispatcher.New(logger)
+
+	h := &permanentHandler{}
+	d.Register("test", h)
+
+	store := dlq.NewMemoryStore()
+	p := New(d, logger, 1, 1, WithDeadLetter(store))
+
+	done := make(chan error, 1)
+	if err := p.SubmitWithAck(events.Message{ID: "5", Type: "test"}, func(err error) { done <- err }); err != nil {
+		t.Fatalf("unexpected submit error: %v", err)
+	}
+	err := <-done
+	p.Stop()
+
+	if !dispatcher.IsPermanent(err) {
+		t.Fatalf("expected permanent error, got %v", err)
+	}
+	if h.calls.Load() != 1 {
+		t.Fatalf("expected handler to be called once, got %d", h.calls.Load())
+	}
+	records, _ := store.List(context.Background(), 0)
+	if len(records) != 1 || records[0].Attempts != 1 {
+		t.Fatalf("expected one dead-letter record with 1 attempt, got %+v", records)
+	}
+}
+
+func TestDeadLetterRedactsErrorsOfEncryptedEvents(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	d := dispatcher.New(logger)
+	d.Register("test", &permanentHandler{})
+
+	store := dlq.NewMemoryStore()
+	p := New(d, logger, 1, 1, WithDeadLetter(store))
+
+	done := make(chan error, 1)
+	if err := p.SubmitWithAck(events.Message{ID: "6", Type: "test", Encrypted: true}, func(err error) { done <- err }); err != nil {
+		t.Fatalf("unexpected submit error: %v", err)
+	}
+	<-done
+	p.Stop()
+
+	records, _ := store.List(context.Background(), 0)
+	if len(records) != 1 || records[0].Error != events.Redacted {
+		t.Fatalf("expected one dead-letter record with a redacted error, got %+v", records)
+	}
+}
+
+func TestWorkerUsesEventRetryPolicy(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	d := dispatcher.New(logger)
+
+	flaky := &fakeHandler{failures: 10}
+	other := &fakeHandler{failures: 10}
+	d.Register("flaky", flaky)
+	d.Register("other", other)
+
+	p := New(d, logger, 1, 2,
+		WithRetryPolicy(ConstantRetry{Interval: time.Millisecond, Retries: 1}),
+		WithEventRetryPolicy("flaky", ConstantRetry{Interval: time.Millisecond, Retries: 5}),
+	)
+
+	done := make(chan error, 2)
+	ack := func(err error) { done <- err }
+	if err := p.SubmitWithAck(events.Message{ID: "6", Type: "flaky"}, ack); err != nil {
+		t.Fatalf("unexpected submit error: %v", err)
+	}
+	if err := p.SubmitWithAck(events.Message{ID: "7", Type: "other"}, ack); err != nil {
+		t.Fatalf("unexpected submit error: %v", err)
+	}
+	<-done
+	<-done
+	p.Stop()
+
+	if flaky.calls.Load() != 6 {
+		t.Fatalf("expected override to allow 6 attempts, got %d", flaky.calls.Load())
+	}
+	if other.calls.Load() != 2 {
+		t.Fatalf("expected default policy to allow 2 attempts, got %d", other.calls.Load())
+	}
+}
+
+func TestWorkerRetriesOnlyFailedHandlers(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	d := dispatcher.New(logger)
+
+	ok := &fakeHandler{failures: 0}
+	flaky := &fakeHandler{failures: 2}
+	invalid := &permanentHandler{}
+	d.Register("test", ok, dispatcher.WithName("ok"))
+	d.Register("test", flaky, dispatcher.WithName("flaky"))
+	d.Register("test", invalid, dispatcher.WithName("invalid"))
+
+	store := dlq.NewMemoryStore()
+	p := New(d, logger, 1, 1,
+		WithDeadLetter(store),
+		WithRetryPolicy(ConstantRetry{Interval: time.Millisecond, Retries: 3}),
+	)
+
+	done := make(chan error, 1)
+	if err := p.SubmitWithAck(events.Message{ID: "8", Type: "test"}, func(err error) { done <- err }); err != nil {
+		t.Fatalf("unexpected submit error: %v", err)
+	}
+	err := <-done
+	p.Stop()
+
+	if ok.calls.Load() != 1 {
+		t.Errorf("expected successful handler to run once, got %d", ok.calls.Load())
+	}
+	if flaky.calls.Load() != 3 {
+		t.Errorf("expected flaky handler to run until it succeeded, got %d", flaky.calls.Load())
+	}
+	if invalid.calls.Load() != 1 {
+		t.Errorf("expected permanently failing handler to run once, got %d", invalid.calls.Load())
+	}
+
+	var dispatchErr *dispatcher.DispatchError
+	if !errors.As(err, &dispatchErr) {
+		t.Fatalf("expected *dispatcher.DispatchError, got %v", err)
+	}
+	if failed := dispatchErr.Failed(); len(failed) != 1 || failed[0] != "invalid" {
+		t.Fatalf("expected only invalid to be reported, got %v", failed)
+	}
+	records, _ := store.List(context.Background(), 0)
+	if len(records) != 1 || records[0].HandlerType != "invalid" {
+		t.Fatalf("expected dead-letter record for invalid, got %+v", records)
+	}
+}
@@ -0,0 +1,70 @@
+package processor
+
+import (
+	"time"
+
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/backoff"
+)
+
+// RetryPolicy decides how often a failed dispatch is retried and how long to
+// wait before each retry. retry counts from 1.
+type RetryPolicy interface {
+	MaxRetries() int
+	Delay(retry int) time.Duration
+}
+
+// ConstantRetry waits the same Interval before every retry.
+type ConstantRetry struct {
+	Interval time.Duration
+	Retries  int
+}
+
+func (r ConstantRetry) MaxRetries() int { return r.Retries }
+
+func (r ConstantRetry) Delay(retry int) time.Duration { return r.Interval }
+
+// LinearRetry waits Interval * retry, capped at MaxDelay when it is set.
+type LinearRetry struct {
+	Interval time.Duration
+	MaxDelay time.Duration
+	Retries  int
+}
+
+func (r LinearRetry) MaxRetries() int { return r.Retries }
+
+func (r LinearRetry) Delay(retry int) time.Duration {
+	return capDelay(r.Interval*time.Duration(retry), r.MaxDelay)
+}
+
+// ExponentialRetry waits Initial * Multiplier^(retry-1), capped at MaxDelay
+// when it is set, with up to Jitter (0 to 1) of the delay removed at random.
+type ExponentialRetry struct {
+	Initial    time.Duration
+	MaxDelay   time.Duration
+	Multiplier float64
+	Jitter     float64
+	Retries    int
+}
+
+func (r ExponentialRetry) MaxRetries() int { return r.Retries }
+
+func (r ExponentialRetry) Delay(retry int) time.Duration {
+	return backoff.Exponential{
+		Initial:    r.Initial,
+		Max:        r.MaxDelay,
+		Multiplier: r.Multiplier,
+		Jitter:     r.Jitter,
+	}.Delay(retry)
+}
+
+// DefaultRetryPolicy retries three times, waiting 100ms, 200ms and 300ms.
+func DefaultRetryPolicy() RetryPolicy {
+	return LinearRetry{Interval: 100 * time.Millisecond, Retries: 3}
+}
+
+func capDelay(d, max time.Duration) time.Duration {
+	if max > 0 && d > max {
+		return max
+	}
+	return d
+}
@@ -0,0 +1,45 @@
+package processor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConstantRetry(t *testing.T) {
+	r := ConstantRetry{Interval: 50 * time.Millisecond, Retries: 2}
+	if r.MaxRetries() != 2 {
+		t.Fatalf("expected 2 retries, got %d", r.MaxRetries())
+	}
+	for retry := 1; retry <= 3; retry++ {
+		if d := r.Delay(retry); d != 50*time.Millisecond {
+			t.Fatalf("retry %d: expected 50ms, got %s", retry, d)
+		}
+	}
+}
+
+func TestLinearRetryCapsDelay(t *testing.T) {
+	r := LinearRetry{Interval: 100 * time.Millisecond, MaxDelay: 250 * time.Millisecond, Retries: 5}
+	expected := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 250 * time.Millisecond, 250 * time.Millisecond}
+	for i, want := range expected {
+		if d := r.Delay(i + 1); d != want {
+			t.Fatalf("retry %d: expected %s, got %s", i+1, want, d)
+		}
+	}
+}
+
+func TestExponentialRetry(t *testing.T) {
+	r := ExponentialRetry{Initial: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond, Multiplier: 2, Retries: 4}
+	expected := []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 40 * time.Millisecond, 50 * time.Millisecond}
+	for i, want := range expected {
+		if d := r.Delay(i + 1); d != want {
+			t.Fatalf("retry %d: expected %s, got %s", i+1, want, d)
+		}
+	}
+
+	jittered := ExponentialRetry{Initial: 10 * time.Millisecond, MaxDelay: 50 * time.Millisecond, Multiplier: 2, Jitter: 1, Retries: 4}
+	for i := 0; i < 100; i++ {
+		if d := jittered.Delay(3); d < 0 || d > 40*time.Millisecond {
+			t.Fatalf("expected jittered delay within [0, 40ms], got %s", d)
+		}
+	}
+}
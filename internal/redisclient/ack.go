@@ -0,0 +1,182 @@
+package redisclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/events"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/processor"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrAckTimeout is returned by PublishAndCollect when not every expected node
+// acknowledged the event in time. The report returned with it says which
+// nodes are missing.
+var ErrAckTimeout = errors.New("timed out waiting for acks")
+
+// Ack is published by a subscriber on the reply channel of an event once its
+// handlers have finished. Duplicate is set by a node that skipped the event
+// because another node was still handling it; that node acks the outcome.
+type Ack struct {
+	EventID   string    `json:"event_id"`
+	Node      string    `json:"node"`
+	Success   bool      `json:"success"`
+	Duplicate bool      `json:"duplicate,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// AckReport lists the acks collected for one event.
+type AckReport struct {
+	EventID string
+	// Receivers is the count Redis returned for the publish.
+	Receivers int64
+	// Acks holds the ack of every node that answered, by node.
+	Acks map[string]Ack
+	// Missing lists the expected nodes that did not answer, sorted.
+	Missing []string
+}
+
+// Failed returns the acks that reported a failure, sorted by node.
+func (r AckReport) Failed() []Ack {
+	var failed []Ack
+	for _, ack := range r.Acks {
+		if !ack.Success {
+			failed = append(failed, ack)
+		}
+	}
+	sort.Slice(failed, func(i, j int) bool { return failed[i].Node < failed[j].Node })
+	return failed
+}
+
+// WithAckTimeout sets how long PublishAndCollect waits for acks when ctx has
+// no earlier deadline. The default is 5 seconds.
+func WithAckTimeout(d time.Duration) PublisherOption {
+	return func(p *Publisher) { p.ackTimeout = d }
+}
+
+// WithAckPrefix sets the prefix of the reply channels PublishAndCollect
+// creates, "acks:" by default. Keep it outside the channels and patterns
+// subscribers listen on.
+func WithAckPrefix(prefix string) PublisherOption {
+	return func(p *Publisher) { p.ackPrefix = prefix }
+}
+
+// PublishAndCollect publishes event with a reply channel and waits until every
+// node in expectedNodes has acknowledged it. Without expected nodes it waits
+// for as many acks as Redis reported receivers, or for one on the streams
+// transport. When the wait ends early it returns the acks collected so far
+// with ErrAckTimeout.
+//
+// Only subscribers created with WithAcks or WithStreamAcks answer.
+func (p *Publisher) PublishAndCollect(ctx context.Context, event events.Message, expectedNodes []string) (AckReport, error) {
+	if event.ID == "" {
+		event.ID = uuid.NewString()
+	}
+	report := AckReport{EventID: event.ID, Acks: make(map[string]Ack)}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.ackTimeout)
+		defer cancel()
+	}
+
+	// subscribe before publishing so no ack is missed
+	reply := p.ackPrefix + event.ID
+	sub := p.client.Subscribe(ctx, reply)
+	defer sub.Close()
+	if _, err := sub.Receive(ctx); err != nil {
+		return report, err
+	}
+	// pubsub reads do not observe ctx, so closing is what unblocks them
+	stop := context.AfterFunc(ctx, func() { _ = sub.Close() })
+	defer stop()
+
+	event.SetReplyTo(reply)
+	_, receivers, err := p.Publish(ctx, event)
+	if err != nil {
+		return report, err
+	}
+	report.Receivers = receivers
+
+	expected := int(receivers)
+	if len(expectedNodes) > 0 {
+		expected = len(expectedNodes)
+	} else if p.transport == TransportStreams {
+		expected = 1
+	}
+	complete := func() bool {
+		if len(expectedNodes) == 0 {
+			return len(report.Acks) >= expected
+		}
+		for _, node := range expectedNodes {
+			if _, ok := report.Acks[node]; !ok {
+				return false
+			}
+		}
+		return true
+	}
+
+	for !complete() {
+		msg, err := sub.ReceiveMessage(ctx)
+		if err != nil {
+			for _, node := range expectedNodes {
+				if _, ok := report.Acks[node]; !ok {
+					report.Missing = append(report.Missing, node)
+				}
+			}
+			sort.Strings(report.Missing)
+			if ctx.Err() != nil {
+				err = ctx.Err()
+			}
+			return report, fmt.Errorf("%w: got %d of %d: %w", ErrAckTimeout, len(report.Acks), expected, err)
+		}
+		var ack Ack
+		if err := json.Unmarshal([]byte(msg.Payload), &ack); err != nil || ack.EventID != event.ID {
+			continue
+		}
+		report.Acks[ack.Node] = ack
+	}
+	return report, nil
+}
+
+// acker publishes the acks of one subscriber node.
+type acker struct {
+	client *redis.Client
+	node   string
+	logger *slog.Logger
+}
+
+// done returns the callback that acknowledges event once the processor has
+// finished with it, or nil when event asks for no ack.
+func (a *acker) done(event events.Message) func(error) {
+	if a == nil || event.ReplyTo() == "" {
+		return nil
+	}
+	return func(err error) { a.ack(event, err) }
+}
+
+func (a *acker) ack(event events.Message, err error) {
+	ack := Ack{EventID: event.ID, Node: a.node, Success: err == nil, Timestamp: time.Now()}
+	if errors.Is(err, processor.ErrInProgress) {
+		ack.Success, ack.Duplicate = true, true
+	} else if err != nil {
+		ack.Error = fmt.Sprint(event.Redact(err.Error()))
+	}
+	data, err := json.Marshal(ack)
+	if err != nil {
+		a.logger.Error("failed to encode ack", "event_id", event.ID, "error", err)
+		return
+	}
+	// acks are sent while the processor drains after ctx was cancelled too
+	if err := a.client.Publish(context.Background(), event.ReplyTo(), data).Err(); err != nil {
+		a.logger.Error("failed to publish ack", "event_id", event.ID, "reply_to", event.ReplyTo(), "error", err)
+	}
+}
@@ -0,0 +1,157 @@
+package redisclient
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/dispatcher"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/events"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/processor"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// startAckingSubscriber runs a subscriber on "events" whose handler fails when
+// fail is set. It acknowledges events as node unless node is empty.
+func startAckingSubscriber(t *testing.T, ctx context.Context, client *redis.Client, node string, fail bool) {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	d := dispatcher.New(logger)
+	h := &countingHandler{}
+	h.fail.Store(fail)
+	d.Register("config.invalidated", h)
+	p := processor.New(d, logger, 1, 10, processor.WithRetryPolicy(processor.ConstantRetry{}))
+	t.Cleanup(p.Stop)
+
+	var opts []SubscriberOption
+	if node != "" {
+		opts = append(opts, WithAcks(node))
+	}
+	sub := NewSubscriber(client, "events", p, logger, opts...)
+	go func() { _ = sub.Start(ctx) }()
+}
+
+func TestPublishAndCollect(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client, err := New(mr.Addr(), 0)
+	if err != nil {
+		t.Fatalf("Failed to create Redis client: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	startAckingSubscriber(t, ctx, client, "node-a", false)
+	startAckingSubscriber(t, ctx, client, "node-b", true)
+	waitFor(t, time.Second, func() bool { return mr.PubSubNumSub("events")["events"] == 2 })
+
+	pub := NewPublisher(client, "test-source", WithChannel("events"))
+	for _, expected := range [][]string{{"node-a", "node-b"}, nil} {
+		report, err := pub.PublishAndCollect(ctx, events.Message{Type: "config.invalidated"}, expected)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if report.Receivers != 2 || len(report.Acks) != 2 || len(report.Missing) != 0 {
+			t.Fatalf("expected acks from both nodes, got %+v", report)
+		}
+		if ack := report.Acks["node-a"]; !ack.Success || ack.EventID != report.EventID {
+			t.Fatalf("expected node-a to succeed, got %+v", ack)
+		}
+		failed := report.Failed()
+		if len(failed) != 1 || failed[0].Node != "node-b" || failed[0].Error == "" {
+			t.Fatalf("expected node-b to report its error, got %+v", failed)
+		}
+	}
+}
+
+func TestPublishAndCollectTimeout(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client, err := New(mr.Addr(), 0)
+	if err != nil {
+		t.Fatalf("Failed to create Redis client: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	startAckingSubscriber(t, ctx, client, "node-a", false)
+	// subscribers without WithAcks handle the event but never answer
+	startAckingSubscriber(t, ctx, client, "", false)
+	waitFor(t, time.Second, func() bool { return mr.PubSubNumSub("events")["events"] == 2 })
+
+	pub := NewPublisher(client, "test-source", WithChannel("events"), WithAckTimeout(200*time.Millisecond))
+	start := time.Now()
+	report, err := pub.PublishAndCollect(ctx, events.Message{Type: "config.invalidated"}, []string{"node-a", "node-c"})
+	if !errors.Is(err, ErrAckTimeout) {
+		t.Fatalf("expected ErrAckTimeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("expected the wait to end after the ack timeout, took %v", elapsed)
+	}
+	if _, ok := report.Acks["node-a"]; !ok || len(report.Acks) != 1 {
+		t.Fatalf("expected the ack of node-a, got %+v", report.Acks)
+	}
+	if len(report.Missing) != 1 || report.Missing[0] != "node-c" {
+		t.Fatalf("expected node-c to be missing, got %v", report.Missing)
+	}
+}
+
+func TestAckRedactsEncryptedErrors(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client, err := New(mr.Addr(), 0)
+	if err != nil {
+		t.Fatalf("Failed to create Redis client: %v", err)
+	}
+	ctx := context.Background()
+	sub := client.Subscribe(ctx, "acks:1")
+	defer sub.Close()
+	if _, err := sub.Receive(ctx); err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+
+	a := &acker{client: client, node: "node-a", logger: slog.New(slog.NewTextHandler(os.Stdout, nil))}
+	event := events.Message{ID: "1", Encrypted: true}
+	if a.done(event) != nil {
+		t.Fatal("expected no ack for an event without a reply channel")
+	}
+	event.SetReplyTo("acks:1")
+	a.done(event)(errors.New("bad card 4242"))
+
+	msg, err := sub.ReceiveMessage(ctx)
+	if err != nil {
+		t.Fatalf("failed to receive ack: %v", err)
+	}
+	if want := `"error":"` + events.Redacted + `"`; !strings.Contains(msg.Payload, want) {
+		t.Fatalf("expected the error to be redacted, got %s", msg.Payload)
+	}
+}
+
+func TestAckReportsInProgressDuplicates(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client, err := New(mr.Addr(), 0)
+	if err != nil {
+		t.Fatalf("Failed to create Redis client: %v", err)
+	}
+	ctx := context.Background()
+	sub := client.Subscribe(ctx, "acks:1")
+	defer sub.Close()
+	if _, err := sub.Receive(ctx); err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+
+	a := &acker{client: client, node: "node-a", logger: slog.New(slog.NewTextHandler(os.Stdout, nil))}
+	event := events.Message{ID: "1"}
+	event.SetReplyTo("acks:1")
+	a.done(event)(processor.ErrInProgress)
+
+	msg, err := sub.ReceiveMessage(ctx)
+	if err != nil {
+		t.Fatalf("failed to receive ack: %v", err)
+	}
+	if !strings.Contains(msg.Payload, `"success":true,"duplicate":true`) || strings.Contains(msg.Payload, `"error"`) {
+		t.Fatalf("expected a successful duplicate ack, got %s", msg.Payload)
+	}
+}
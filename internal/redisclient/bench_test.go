@@ -0,0 +1,108 @@
+package redisclient
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/events"
+)
+
+// genericMessage is how events were decoded before payloads were kept raw.
+type genericMessage struct {
+	ID        string            `json:"id"`
+	Type      string            `json:"type"`
+	Source    string            `json:"source"`
+	Timestamp time.Time         `json:"timestamp"`
+	Key       string            `json:"key,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Payload   any               `json:"payload"`
+}
+
+type benchOrder struct {
+	OrderID  string  `json:"order_id"`
+	Customer string  `json:"customer"`
+	Total    float64 `json:"total"`
+	Items    []struct {
+		SKU      string  `json:"sku"`
+		Quantity int     `json:"quantity"`
+		Price    float64 `json:"price"`
+	} `json:"items"`
+}
+
+func benchMessage(items int) []byte {
+	payload := map[string]any{"order_id": "o-1", "customer": "c-42", "total": 99.5}
+	list := make([]map[string]any, items)
+	for i := range list {
+		list[i] = map[string]any{"sku": fmt.Sprintf("sku-%d", i), "quantity": i, "price": 1.25}
+	}
+	payload["items"] = list
+	data, _ := json.Marshal(events.Message{
+		ID: "550e8400-e29b-41d4-a716-446655440000", Type: "order.created", Source: "bench",
+		Timestamp: time.Now(), Payload: payload,
+	})
+	return data
+}
+
+// BenchmarkDecode compares decoding what the subscriber receives with the
+// payload kept raw against decoding it into generic values, both for events
+// no handler looks into (envelope) and for events a typed handler consumes.
+func BenchmarkDecode(b *testing.B) {
+	for _, items := range []int{1, 20} {
+		data := benchMessage(items)
+
+		b.Run(fmt.Sprintf("items=%d/envelope/raw", items), func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(data)))
+			for b.Loop() {
+				var m events.Message
+				if err := json.Unmarshal(data, &m); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("items=%d/envelope/generic", items), func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(data)))
+			for b.Loop() {
+				var m genericMessage
+				if err := json.Unmarshal(data, &m); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("items=%d/typed/raw", items), func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(data)))
+			for b.Loop() {
+				var m events.Message
+				if err := json.Unmarshal(data, &m); err != nil {
+					b.Fatal(err)
+				}
+				var order benchOrder
+				if err := m.DecodePayload(&order); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("items=%d/typed/generic", items), func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(data)))
+			for b.Loop() {
+				var m genericMessage
+				if err := json.Unmarshal(data, &m); err != nil {
+					b.Fatal(err)
+				}
+				reencoded, err := json.Marshal(m.Payload)
+				if err != nil {
+					b.Fatal(err)
+				}
+				var order benchOrder
+				if err := json.Unmarshal(reencoded, &order); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -3,7 +3,7 @@ package redisclient
 import (
 	"testing"
 
-	"github.com/alicebob/miniredis"
+	"github.com/alicebob/miniredis/v2"
 )
 
 func TestNewClient(t *testing.T) {
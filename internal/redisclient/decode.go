@@ -0,0 +1,58 @@
+package redisclient
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/codec"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/events"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/keyring"
+)
+
+// ErrNoDecryptionKeys rejects an encrypted message received without a
+// decryption keyring.
+var ErrNoDecryptionKeys = errors.New("encrypted message but no decryption keys")
+
+// decoder turns received messages into events. Every field is optional.
+type decoder struct {
+	observe  codec.CompressionObserver
+	verifier *Verifier
+	keys     *keyring.Keyring
+}
+
+// decode checks the signature of data, then decrypts, decompresses and
+// decodes it into event. Only the policy for unsigned messages needs the
+// decoded event type; a signed message is verified before any work is done
+// on its body.
+func (d decoder) decode(data []byte, event *events.Message) error {
+	f, err := codec.ParseFrame(data)
+	if err != nil {
+		return err
+	}
+	if d.verifier != nil && f.Signed() {
+		if err := d.verifier.verifySigned(f); err != nil {
+			return err
+		}
+	}
+	plain := f
+	if f.Encrypted() {
+		if d.keys == nil {
+			return ErrNoDecryptionKeys
+		}
+		key, err := d.keys.Key(f.EncryptionKeyID())
+		if err != nil {
+			return fmt.Errorf("%w: %w", codec.ErrDecrypt, err)
+		}
+		if plain, err = f.Decrypt(key); err != nil {
+			return err
+		}
+	}
+	if err := codec.DecodeFrame(plain, event, d.observe); err != nil {
+		return err
+	}
+	event.Encrypted = f.Encrypted()
+	if d.verifier != nil && !f.Signed() {
+		return d.verifier.verifyUnsigned(event.Type)
+	}
+	return nil
+}
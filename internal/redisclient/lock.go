@@ -0,0 +1,36 @@
+package redisclient
+
+import (
+	"context"
+	"time"
+
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/dedup"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Locker is a dispatcher.Locker backed by the claims of a dedup.Redis store.
+// A claim is a key set with SET NX PX to a random token; it is only completed
+// or released by the holder of that token, so a node whose claim expired
+// cannot clobber the claim of the node that took over.
+type Locker struct {
+	claims *dedup.Redis
+}
+
+func NewLocker(client *redis.Client, prefix string) *Locker {
+	// completed claims are kept for the ttl passed to Complete instead
+	return &Locker{claims: dedup.NewRedis(client, prefix, 0)}
+}
+
+func (l *Locker) Acquire(ctx context.Context, key string, ttl time.Duration) (string, bool, error) {
+	token, state, err := l.claims.Claim(ctx, key, ttl)
+	return token, state == dedup.Claimed, err
+}
+
+func (l *Locker) Complete(ctx context.Context, key, token string, ttl time.Duration) error {
+	return l.claims.CompleteFor(ctx, key, token, ttl)
+}
+
+func (l *Locker) Release(ctx context.Context, key, token string) error {
+	return l.claims.Release(ctx, key, token)
+}
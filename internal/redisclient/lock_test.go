@@ -0,0 +1,67 @@
+package redisclient
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestLocker(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client, err := New(mr.Addr(), 0)
+	if err != nil {
+		t.Fatalf("Failed to create Redis client: %v", err)
+	}
+	ctx := context.Background()
+	node1 := NewLocker(client, "lock:")
+	node2 := NewLocker(client, "lock:")
+
+	token, ok, err := node1.Acquire(ctx, "email:1", time.Minute)
+	if err != nil || !ok {
+		t.Fatalf("expected the first claim to win, got %v (%v)", ok, err)
+	}
+	if _, ok, _ := node2.Acquire(ctx, "email:1", time.Minute); ok {
+		t.Fatal("expected a held claim to be refused")
+	}
+
+	// a release with the wrong token leaves the claim alone
+	if err := node2.Release(ctx, "email:1", "not-the-token"); err != nil {
+		t.Fatalf("unexpected release error: %v", err)
+	}
+	if !mr.Exists("lock:email:1") {
+		t.Fatal("expected the claim to survive a foreign release")
+	}
+	if err := node1.Release(ctx, "email:1", token); err != nil {
+		t.Fatalf("unexpected release error: %v", err)
+	}
+	token, ok, _ = node2.Acquire(ctx, "email:1", time.Minute)
+	if !ok {
+		t.Fatal("expected a released claim to be claimable")
+	}
+
+	if err := node2.Complete(ctx, "email:1", token, time.Hour); err != nil {
+		t.Fatalf("unexpected complete error: %v", err)
+	}
+	if v, _ := mr.Get("lock:email:1"); v != "done" || mr.TTL("lock:email:1") != time.Hour {
+		t.Fatalf("expected a completed claim kept for an hour, got %q for %s", v, mr.TTL("lock:email:1"))
+	}
+	if _, ok, _ := node1.Acquire(ctx, "email:1", time.Minute); ok {
+		t.Fatal("expected a completed claim to be refused")
+	}
+
+	// a crashed winner's claim expires and can be taken over
+	_, _, _ = node1.Acquire(ctx, "email:2", time.Minute)
+	mr.FastForward(time.Minute)
+	token, ok, _ = node2.Acquire(ctx, "email:2", time.Minute)
+	if !ok {
+		t.Fatal("expected an expired claim to be claimable")
+	}
+	if err := node1.Complete(ctx, "email:2", "stale-token", time.Hour); err != nil {
+		t.Fatalf("unexpected complete error: %v", err)
+	}
+	if v, _ := mr.Get("lock:email:2"); v != token {
+		t.Fatalf("expected a stale holder not to complete the new claim, got %q", v)
+	}
+}
@@ -0,0 +1,209 @@
+package redisclient
+
+import (
+	"context"
+	"time"
+
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/codec"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/events"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/keyring"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/tracing"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// PublishHook runs before every publish and may modify the event. Returning an
+// error aborts the publish.
+type PublishHook func(ctx context.Context, event *events.Message) error
+
+// Publisher builds complete event envelopes and publishes them to Redis.
+type Publisher struct {
+	client    *redis.Client
+	source    string
+	channel   string
+	transport string
+	maxLen    int64
+	codec     codec.Codec
+	hooks     []PublishHook
+
+	compression string
+	threshold   int
+	observe     codec.CompressionObserver
+	signing     *keyring.Keyring
+	encryption  *keyring.Keyring
+	ackTimeout  time.Duration
+	ackPrefix   string
+}
+
+type PublisherOption func(*Publisher)
+
+// WithChannel sets the channel, or stream, events are published to.
+func WithChannel(channel string) PublisherOption {
+	return func(p *Publisher) { p.channel = channel }
+}
+
+// WithCodec sets the codec used to encode events. Subscribers detect the
+// codec of every message, so publishers using different codecs can share a
+// channel.
+func WithCodec(c codec.Codec) PublisherOption {
+	return func(p *Publisher) { p.codec = c }
+}
+
+// WithCompression compresses the encoded body of events of at least threshold
+// bytes with encoding (codec.Gzip, codec.Zstd or codec.Snappy). Subscribers
+// decompress messages transparently. Bodies that do not get smaller are sent
+// uncompressed.
+func WithCompression(encoding string, threshold int) PublisherOption {
+	return func(p *Publisher) {
+		p.compression = encoding
+		p.threshold = threshold
+	}
+}
+
+// WithPublisherCompressionObserver reports the size of every compressed event
+// before and after compression.
+func WithPublisherCompressionObserver(observe codec.CompressionObserver) PublisherOption {
+	return func(p *Publisher) { p.observe = observe }
+}
+
+// WithSigning signs every event with HMAC-SHA256 under the current key of
+// keys, read at publish time so the key can be rotated while publishing.
+func WithSigning(keys *keyring.Keyring) PublisherOption {
+	return func(p *Publisher) { p.signing = keys }
+}
+
+// WithEncryption encrypts every event with AES-GCM under the current key of
+// keys. Only the envelope's frame parameters, such as the content type and key
+// IDs, stay readable on the wire.
+func WithEncryption(keys *keyring.Keyring) PublisherOption {
+	return func(p *Publisher) { p.encryption = keys }
+}
+
+// WithPublishHook adds a hook run before each publish. Hooks run in the order
+// they were added.
+func WithPublishHook(hook PublishHook) PublisherOption {
+	return func(p *Publisher) { p.hooks = append(p.hooks, hook) }
+}
+
+// WithTransport selects TransportPubSub (the default) or TransportStreams.
+func WithTransport(transport string) PublisherOption {
+	return func(p *Publisher) { p.transport = transport }
+}
+
+// WithStreamMaxLen trims the stream to about n entries on every publish with
+// TransportStreams, so it does not grow without bound. Entries trimmed before
+// every consumer group has read them are lost to those groups.
+func WithStreamMaxLen(n int64) PublisherOption {
+	return func(p *Publisher) { p.maxLen = n }
+}
+
+func NewPublisher(client *redis.Client, source string, opts ...PublisherOption) *Publisher {
+	p := &Publisher{
+		client:     client,
+		source:     source,
+		channel:    "broadcast.events",
+		transport:  TransportPubSub,
+		codec:      codec.JSON{},
+		ackTimeout: 5 * time.Second,
+		ackPrefix:  "acks:",
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Publish fills in the ID, Timestamp and Source of event when they are unset,
+// encodes it and publishes it. It returns the ID the event was published
+// with and the number of subscribers Redis reported as receiving it; on the
+// streams transport delivery is asynchronous and the count is always 0.
+//
+// The publish span is a child of the span in ctx and its trace context is
+// carried in the event, so subscriber spans join the same trace.
+func (p *Publisher) Publish(ctx context.Context, event events.Message) (id string, receivers int64, err error) {
+	return p.PublishTo(ctx, p.channel, event)
+}
+
+// PublishTo publishes event like Publish, but to channel, or to the stream of
+// that name on the streams transport, instead of the publisher's channel.
+func (p *Publisher) PublishTo(ctx context.Context, channel string, event events.Message) (id string, receivers int64, err error) {
+	if event.ID == "" {
+		event.ID = uuid.NewString()
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	if event.Source == "" {
+		event.Source = p.source
+	}
+
+	for _, hook := range p.hooks {
+		if err := hook(ctx, &event); err != nil {
+			return "", 0, err
+		}
+	}
+
+	ctx, span := tracing.Tracer().Start(ctx, "publish "+channel,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		tracing.Attributes(event),
+		trace.WithAttributes(attribute.String("messaging.destination.name", channel)),
+	)
+	defer func() { tracing.End(span, err) }()
+	tracing.Inject(ctx, &event)
+
+	data, err := p.encode(event)
+	if err != nil {
+		return "", 0, err
+	}
+
+	if p.transport == TransportStreams {
+		_, err := AddToStream(ctx, p.client, channel, data, p.maxLen)
+		return event.ID, 0, err
+	}
+	receivers, err = p.client.Publish(ctx, channel, data).Result()
+	return event.ID, receivers, err
+}
+
+func (p *Publisher) encode(event events.Message) ([]byte, error) {
+	body, err := p.codec.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+	f := codec.Frame{ContentType: p.codec.ContentType(), Body: body}
+	if p.compression != "" && len(body) >= p.threshold {
+		if f, err = f.Compress(p.compression); err != nil {
+			return nil, err
+		}
+		if p.observe != nil && f.Encoding() != "" {
+			p.observe(f.Encoding(), len(f.Body), len(body))
+		}
+	}
+	if p.encryption != nil {
+		kid, key, err := p.encryption.Current()
+		if err != nil {
+			return nil, err
+		}
+		if f, err = f.Encrypt(kid, key); err != nil {
+			return nil, err
+		}
+	}
+	if p.signing != nil {
+		kid, key, err := p.signing.Current()
+		if err != nil {
+			return nil, err
+		}
+		f = f.Sign(kid, key)
+	}
+	return f.Bytes(), nil
+}
+
+// Publish publishes env with its typed payload, filling in the envelope like
+// Publisher.Publish.
+func Publish[T any](ctx context.Context, p *Publisher, env events.Envelope[T]) (string, int64, error) {
+	event := env.Message
+	event.Payload = env.Payload
+	return p.Publish(ctx, event)
+}
@@ -0,0 +1,202 @@
+package redisclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/events"
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestPublisherFillsEnvelope(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client, err := New(mr.Addr(), 0)
+	if err != nil {
+		t.Fatalf("Failed to create Redis client: %v", err)
+	}
+	ctx := context.Background()
+
+	sub := client.Subscribe(ctx, "test-channel")
+	defer sub.Close()
+	if _, err := sub.Receive(ctx); err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+
+	p := NewPublisher(client, "test-source", WithChannel("test-channel"))
+	id, receivers, err := p.Publish(ctx, events.Message{Type: "demo.message", Payload: "hello"})
+	if err != nil {
+		t.Fatalf("unexpected publish error: %v", err)
+	}
+	if receivers != 1 {
+		t.Fatalf("expected 1 receiver, got %d", receivers)
+	}
+
+	msg, err := sub.ReceiveMessage(ctx)
+	if err != nil {
+		t.Fatalf("failed to receive message: %v", err)
+	}
+	var event events.Message
+	if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
+		t.Fatalf("failed to decode message: %v", err)
+	}
+	if event.ID == "" || event.ID != id {
+		t.Errorf("expected ID to be filled in and returned, got %q and %q", event.ID, id)
+	}
+	if event.Timestamp.IsZero() {
+		t.Error("expected Timestamp to be filled in")
+	}
+	if event.Source != "test-source" {
+		t.Errorf("expected Source to be 'test-source', got '%s'", event.Source)
+	}
+}
+
+func TestPublishTo(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client, err := New(mr.Addr(), 0)
+	if err != nil {
+		t.Fatalf("Failed to create Redis client: %v", err)
+	}
+	ctx := context.Background()
+
+	sub := client.Subscribe(ctx, "orders.paid")
+	defer sub.Close()
+	if _, err := sub.Receive(ctx); err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+
+	p := NewPublisher(client, "test-source", WithChannel("test-channel"))
+	if _, receivers, err := p.PublishTo(ctx, "orders.paid", events.Message{Type: "demo.message"}); err != nil || receivers != 1 {
+		t.Fatalf("expected 1 receiver on orders.paid, got %d (%v)", receivers, err)
+	}
+	msg, err := sub.ReceiveMessage(ctx)
+	if err != nil {
+		t.Fatalf("failed to receive message: %v", err)
+	}
+	if msg.Channel != "orders.paid" {
+		t.Errorf("expected the message on orders.paid, got %s", msg.Channel)
+	}
+}
+
+func TestPublisherKeepsExplicitFields(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client, err := New(mr.Addr(), 0)
+	if err != nil {
+		t.Fatalf("Failed to create Redis client: %v", err)
+	}
+
+	var seen events.Message
+	ts := time.Date(2026, 2, 22, 10, 0, 0, 0, time.UTC)
+	p := NewPublisher(client, "test-source", WithPublishHook(func(ctx context.Context, event *events.Message) error {
+		seen = *event
+		return nil
+	}))
+	if _, _, err := p.Publish(context.Background(), events.Message{ID: "fixed", Source: "other", Timestamp: ts}); err != nil {
+		t.Fatalf("unexpected publish error: %v", err)
+	}
+	if seen.ID != "fixed" || seen.Source != "other" || !seen.Timestamp.Equal(ts) {
+		t.Fatalf("expected explicit fields to be kept, got %+v", seen)
+	}
+}
+
+func TestPublisherHookErrorAbortsPublish(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client, err := New(mr.Addr(), 0)
+	if err != nil {
+		t.Fatalf("Failed to create Redis client: %v", err)
+	}
+
+	hookErr := errors.New("rejected")
+	p := NewPublisher(client, "test-source",
+		WithTransport(TransportStreams),
+		WithPublishHook(func(ctx context.Context, event *events.Message) error { return hookErr }),
+	)
+	if _, _, err := p.Publish(context.Background(), events.Message{Type: "demo.message"}); !errors.Is(err, hookErr) {
+		t.Fatalf("expected hook error, got %v", err)
+	}
+	if mr.Exists("broadcast.events") {
+		t.Fatal("expected nothing to be published")
+	}
+}
+
+func TestPublisherStreamTransport(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client, err := New(mr.Addr(), 0)
+	if err != nil {
+		t.Fatalf("Failed to create Redis client: %v", err)
+	}
+
+	p := NewPublisher(client, "test-source", WithChannel("events"), WithTransport(TransportStreams))
+	if _, _, err := p.Publish(context.Background(), events.Message{Type: "demo.message"}); err != nil {
+		t.Fatalf("unexpected publish error: %v", err)
+	}
+
+	n, err := client.XLen(context.Background(), "events").Result()
+	if err != nil {
+		t.Fatalf("XLEN failed: %v", err)
+	}
+	if n != 1 {
+		t.Fatalf("expected 1 stream entry, got %d", n)
+	}
+}
+
+func TestPublisherTrimsStream(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client, err := New(mr.Addr(), 0)
+	if err != nil {
+		t.Fatalf("Failed to create Redis client: %v", err)
+	}
+
+	p := NewPublisher(client, "test-source", WithChannel("events"), WithTransport(TransportStreams), WithStreamMaxLen(2))
+	for i := 0; i < 5; i++ {
+		if _, _, err := p.Publish(context.Background(), events.Message{Type: "demo.message"}); err != nil {
+			t.Fatalf("unexpected publish error: %v", err)
+		}
+	}
+
+	// miniredis trims exactly where Redis trims to whole macro nodes
+	n, err := client.XLen(context.Background(), "events").Result()
+	if err != nil {
+		t.Fatalf("XLEN failed: %v", err)
+	}
+	if n != 2 {
+		t.Fatalf("expected the stream to be trimmed to 2 entries, got %d", n)
+	}
+}
+
+func TestPublishTyped(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client, err := New(mr.Addr(), 0)
+	if err != nil {
+		t.Fatalf("Failed to create Redis client: %v", err)
+	}
+
+	type greeting struct {
+		Text  string `json:"text"`
+		Count int    `json:"count"`
+	}
+	var seen events.Message
+	p := NewPublisher(client, "test-source", WithPublishHook(func(ctx context.Context, event *events.Message) error {
+		seen = *event
+		return nil
+	}))
+
+	env := events.Envelope[greeting]{Message: events.Message{Type: "greeting", Key: "k"}, Payload: greeting{Text: "hi", Count: 2}}
+	id, _, err := Publish(context.Background(), p, env)
+	if err != nil {
+		t.Fatalf("unexpected publish error: %v", err)
+	}
+	if seen.Type != "greeting" || seen.Key != "k" || seen.ID == "" || seen.ID != id {
+		t.Fatalf("expected envelope fields to be published, got %+v", seen)
+	}
+
+	got, err := events.Open[greeting](seen)
+	if err != nil {
+		t.Fatalf("unexpected decode error: %v", err)
+	}
+	if got.Payload != env.Payload {
+		t.Fatalf("expected payload %+v, got %+v", env.Payload, got.Payload)
+	}
+}
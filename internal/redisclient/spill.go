@@ -0,0 +1,104 @@
+package redisclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/codec"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/events"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/keyring"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ListSpiller is a processor.Spiller backed by a Redis list. Events are
+// appended with RPUSH and taken back oldest first, so anything still spilled
+// when a subscriber stops is picked up by the next run.
+//
+// Pop moves an event to <key>:processing with LMOVE and removes it from there
+// once it decoded, so an event that cannot be decoded is kept in that list
+// instead of being lost. Call Recover before the first Pop to return what a
+// crashed run left there.
+type ListSpiller struct {
+	client     *redis.Client
+	key        string
+	processing string
+	keys       *keyring.Keyring
+}
+
+type SpillerOption func(*ListSpiller)
+
+// WithSpillEncryption encrypts events that were encrypted on the wire under
+// the current key of keys before they are spilled, so they never sit in Redis
+// in the clear. Without it such events cannot be spilled.
+func WithSpillEncryption(keys *keyring.Keyring) SpillerOption {
+	return func(s *ListSpiller) { s.keys = keys }
+}
+
+func NewListSpiller(client *redis.Client, key string, opts ...SpillerOption) *ListSpiller {
+	s := &ListSpiller{client: client, key: key, processing: key + ":processing"}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *ListSpiller) Push(ctx context.Context, event events.Message) error {
+	data, err := codec.Seal(event, s.keys)
+	if err != nil {
+		return err
+	}
+	return s.client.RPush(ctx, s.key, data).Err()
+}
+
+// PushFront returns event to the head of the list, so it is popped next.
+func (s *ListSpiller) PushFront(ctx context.Context, event events.Message) error {
+	data, err := codec.Seal(event, s.keys)
+	if err != nil {
+		return err
+	}
+	return s.client.LPush(ctx, s.key, data).Err()
+}
+
+func (s *ListSpiller) Pop(ctx context.Context) (events.Message, bool, error) {
+	var event events.Message
+	data, err := s.client.LMove(ctx, s.key, s.processing, "LEFT", "RIGHT").Bytes()
+	if errors.Is(err, redis.Nil) {
+		return event, false, nil
+	}
+	if err != nil {
+		return event, false, err
+	}
+	if err := codec.Open(data, s.keys, &event); err != nil {
+		return event, false, fmt.Errorf("spilled event kept in %s: %w", s.processing, err)
+	}
+	if err := s.client.LRem(ctx, s.processing, -1, data).Err(); err != nil {
+		return event, false, err
+	}
+	return event, true, nil
+}
+
+// Recover moves every event left in <key>:processing back to the head of the
+// list, oldest first, and returns how many it moved. A run that crashed
+// between popping an event and handing it to the processor leaves it there.
+// Undecodable events are moved too and kept in processing again when popped.
+// Call it only while no other run pops from the same key.
+func (s *ListSpiller) Recover(ctx context.Context) (int, error) {
+	n := 0
+	for {
+		err := s.client.LMove(ctx, s.processing, s.key, "RIGHT", "LEFT").Err()
+		if errors.Is(err, redis.Nil) {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+		n++
+	}
+}
+
+// Len returns the number of events waiting in the list.
+func (s *ListSpiller) Len(ctx context.Context) (int64, error) {
+	return s.client.LLen(ctx, s.key).Result()
+}
@@ -0,0 +1,146 @@
+package redisclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/codec"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/events"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/keyring"
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestListSpiller(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client, err := New(mr.Addr(), 0)
+	if err != nil {
+		t.Fatalf("Failed to create Redis client: %v", err)
+	}
+	ctx := context.Background()
+	s := NewListSpiller(client, "spill")
+
+	if _, ok, err := s.Pop(ctx); err != nil || ok {
+		t.Fatalf("expected an empty list, got ok=%v err=%v", ok, err)
+	}
+	for _, id := range []string{"1", "2"} {
+		if err := s.Push(ctx, events.Message{ID: id, Type: "demo.message"}); err != nil {
+			t.Fatalf("unexpected push error: %v", err)
+		}
+	}
+	if n, err := s.Len(ctx); err != nil || n != 2 {
+		t.Fatalf("expected 2 spilled events, got %d (%v)", n, err)
+	}
+
+	event, ok, err := s.Pop(ctx)
+	if err != nil || !ok {
+		t.Fatalf("expected an event, got ok=%v err=%v", ok, err)
+	}
+	if event.ID != "1" || event.Type != "demo.message" {
+		t.Fatalf("expected the oldest event first, got %+v", event)
+	}
+
+	// an event handed back goes before the ones spilled after it
+	if err := s.PushFront(ctx, event); err != nil {
+		t.Fatalf("unexpected push error: %v", err)
+	}
+	if event, _, err = s.Pop(ctx); err != nil || event.ID != "1" {
+		t.Fatalf("expected the returned event first, got %+v (%v)", event, err)
+	}
+	if n, err := client.LLen(ctx, "spill:processing").Result(); err != nil || n != 0 {
+		t.Fatalf("expected no events left in processing, got %d (%v)", n, err)
+	}
+}
+
+func TestListSpillerRecover(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client, err := New(mr.Addr(), 0)
+	if err != nil {
+		t.Fatalf("Failed to create Redis client: %v", err)
+	}
+	ctx := context.Background()
+	s := NewListSpiller(client, "spill")
+
+	for _, id := range []string{"1", "2", "3"} {
+		if err := s.Push(ctx, events.Message{ID: id}); err != nil {
+			t.Fatalf("unexpected push error: %v", err)
+		}
+	}
+	// a crashed run left the two oldest events mid-pop
+	for range 2 {
+		if err := client.LMove(ctx, "spill", "spill:processing", "LEFT", "RIGHT").Err(); err != nil {
+			t.Fatalf("failed to move: %v", err)
+		}
+	}
+
+	if n, err := s.Recover(ctx); err != nil || n != 2 {
+		t.Fatalf("expected 2 events recovered, got %d (%v)", n, err)
+	}
+	for _, want := range []string{"1", "2", "3"} {
+		if event, ok, err := s.Pop(ctx); err != nil || !ok || event.ID != want {
+			t.Fatalf("expected event %s, got %+v ok=%v err=%v", want, event, ok, err)
+		}
+	}
+}
+
+func TestListSpillerKeepsUndecodableEvents(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client, err := New(mr.Addr(), 0)
+	if err != nil {
+		t.Fatalf("Failed to create Redis client: %v", err)
+	}
+	ctx := context.Background()
+	s := NewListSpiller(client, "spill")
+
+	if err := client.RPush(ctx, "spill", "not an event").Err(); err != nil {
+		t.Fatalf("failed to push: %v", err)
+	}
+	if err := s.Push(ctx, events.Message{ID: "1", Type: "demo.message"}); err != nil {
+		t.Fatalf("unexpected push error: %v", err)
+	}
+
+	if _, ok, err := s.Pop(ctx); err == nil || ok {
+		t.Fatalf("expected a decode error, got ok=%v err=%v", ok, err)
+	}
+	if event, ok, err := s.Pop(ctx); err != nil || !ok || event.ID != "1" {
+		t.Fatalf("expected the next event, got %+v ok=%v err=%v", event, ok, err)
+	}
+	kept, err := client.LRange(ctx, "spill:processing", 0, -1).Result()
+	if err != nil || len(kept) != 1 || kept[0] != "not an event" {
+		t.Fatalf("expected the undecodable event to be kept, got %q (%v)", kept, err)
+	}
+}
+
+func TestListSpillerEncryptsEncryptedEvents(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client, err := New(mr.Addr(), 0)
+	if err != nil {
+		t.Fatalf("Failed to create Redis client: %v", err)
+	}
+	ctx := context.Background()
+	keys := keyring.New()
+	keys.Add("k1", bytes.Repeat([]byte{1}, 32))
+	s := NewListSpiller(client, "spill", WithSpillEncryption(keys))
+
+	event := events.Message{ID: "1", Type: "customer.updated", Payload: "jane@example.com", Encrypted: true}
+	if err := NewListSpiller(client, "spill").Push(ctx, event); !errors.Is(err, codec.ErrNoSealKeys) {
+		t.Fatalf("expected an encrypted event to need keys, got %v", err)
+	}
+	if err := s.Push(ctx, event); err != nil {
+		t.Fatalf("unexpected push error: %v", err)
+	}
+	stored, err := client.LIndex(ctx, "spill", 0).Result()
+	if err != nil || strings.Contains(stored, "example.com") {
+		t.Fatalf("expected the spilled event to be encrypted, got %q (%v)", stored, err)
+	}
+
+	got, ok, err := s.Pop(ctx)
+	if err != nil || !ok {
+		t.Fatalf("expected an event, got ok=%v err=%v", ok, err)
+	}
+	if !got.Encrypted || got.Redact(got.Payload) != events.Redacted {
+		t.Fatalf("expected the event to stay marked encrypted, got %+v", got)
+	}
+}
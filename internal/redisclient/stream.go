@@ -0,0 +1,327 @@
+package redisclient
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/backoff"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/codec"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/events"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/keyring"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/processor"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/tracing"
+
+	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// StreamField is the stream entry field holding the encoded event.
+const StreamField = "data"
+
+const (
+	TransportPubSub  = "pubsub"
+	TransportStreams = "streams"
+)
+
+// AddToStream appends an encoded event to stream with XADD and returns the
+// entry ID assigned by Redis. With a positive maxLen the stream is trimmed to
+// about maxLen entries, dropping the oldest; 0 leaves it untrimmed.
+func AddToStream(ctx context.Context, client *redis.Client, stream string, data []byte, maxLen int64) (string, error) {
+	return client.XAdd(ctx, &redis.XAddArgs{
+		Stream: stream,
+		MaxLen: maxLen,
+		Approx: true,
+		Values: map[string]any{StreamField: data},
+	}).Result()
+}
+
+// StreamSubscriber consumes events from a Redis stream as part of a consumer
+// group. Entries are acknowledged only after the processor has handled them;
+// entries left pending by a crashed or slow consumer are reclaimed with
+// XAUTOCLAIM. Entries this consumer still has queued or running are never
+// submitted twice.
+type StreamSubscriber struct {
+	client        *redis.Client
+	stream        string
+	group         string
+	consumer      string
+	processor     *processor.Processor
+	logger        *slog.Logger
+	block         time.Duration
+	batchSize     int64
+	claimMinIdle  time.Duration
+	claimInterval time.Duration
+	backoff       backoff.Exponential
+	decoder       decoder
+	acker         *acker
+
+	mu       sync.Mutex
+	inFlight map[string]struct{}
+}
+
+type StreamOption func(*StreamSubscriber)
+
+// WithBlock sets how long a single XREADGROUP call waits for new entries.
+func WithBlock(d time.Duration) StreamOption {
+	return func(s *StreamSubscriber) { s.block = d }
+}
+
+// WithBatchSize sets the maximum number of entries read or claimed at once.
+func WithBatchSize(n int64) StreamOption {
+	return func(s *StreamSubscriber) { s.batchSize = n }
+}
+
+// WithClaimMinIdle sets how long an entry must be pending before another
+// consumer may claim it. It must exceed the longest time an event can take to
+// be handled, retries included, or an entry still being handled elsewhere is
+// handled twice.
+func WithClaimMinIdle(d time.Duration) StreamOption {
+	return func(s *StreamSubscriber) { s.claimMinIdle = d }
+}
+
+// WithStreamBackoff sets the delays used between retries after a failed read.
+func WithStreamBackoff(b backoff.Exponential) StreamOption {
+	return func(s *StreamSubscriber) { s.backoff = b }
+}
+
+// WithStreamCompressionObserver reports the size of every compressed entry
+// before and after decompression.
+func WithStreamCompressionObserver(observe codec.CompressionObserver) StreamOption {
+	return func(s *StreamSubscriber) { s.decoder.observe = observe }
+}
+
+// WithStreamVerifier checks entry signatures with v. Rejected entries are
+// acknowledged without being processed.
+func WithStreamVerifier(v *Verifier) StreamOption {
+	return func(s *StreamSubscriber) { s.decoder.verifier = v }
+}
+
+// WithStreamDecryption decrypts encrypted entries with the keys in keys.
+// Without it, encrypted entries are rejected.
+func WithStreamDecryption(keys *keyring.Keyring) StreamOption {
+	return func(s *StreamSubscriber) { s.decoder.keys = keys }
+}
+
+// WithStreamAcks acknowledges events that carry a reply channel as node once
+// they have been handled, like WithAcks.
+func WithStreamAcks(node string) StreamOption {
+	return func(s *StreamSubscriber) { s.acker = &acker{client: s.client, node: node, logger: s.logger} }
+}
+
+// WithClaimInterval sets how often pending entries are checked for claiming.
+func WithClaimInterval(d time.Duration) StreamOption {
+	return func(s *StreamSubscriber) { s.claimInterval = d }
+}
+
+func NewStreamSubscriber(client *redis.Client, stream, group, consumer string, processor *processor.Processor, logger *slog.Logger, opts ...StreamOption) *StreamSubscriber {
+	s := &StreamSubscriber{
+		client:        client,
+		stream:        stream,
+		group:         group,
+		consumer:      consumer,
+		processor:     processor,
+		logger:        logger,
+		block:         time.Second,
+		batchSize:     10,
+		claimMinIdle:  30 * time.Second,
+		claimInterval: 5 * time.Second,
+		backoff:       backoff.Default(),
+		inFlight:      make(map[string]struct{}),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Start consumes the stream until ctx is cancelled. Read failures are retried
+// with jittered exponential backoff, recreating the consumer group if needed.
+func (s *StreamSubscriber) Start(ctx context.Context) error {
+	s.logger.Info("consuming redis stream", "stream", s.stream, "group", s.group, "consumer", s.consumer)
+
+	var lastClaim time.Time
+	ready := false
+	attempt := 0
+	for {
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		err := s.poll(ctx, &ready, &lastClaim)
+		if err == nil {
+			if attempt > 0 {
+				s.logger.Info("reconnected to redis", "stream", s.stream, "attempts", attempt)
+				attempt = 0
+			}
+			continue
+		}
+		// if context was cancelled, exit cleanly
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		ready = false
+		attempt++
+		delay := s.backoff.Delay(attempt)
+		s.logger.Warn("failed to read redis stream, retrying", "stream", s.stream, "error", err, "attempt", attempt, "delay", delay)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+	}
+}
+
+// poll makes sure the group exists, claims idle entries when due and reads one
+// batch of new entries.
+func (s *StreamSubscriber) poll(ctx context.Context, ready *bool, lastClaim *time.Time) error {
+	if !*ready {
+		if err := s.ensureGroup(ctx); err != nil {
+			return err
+		}
+		*ready = true
+	}
+
+	if time.Since(*lastClaim) >= s.claimInterval {
+		if err := s.claim(ctx); err != nil {
+			return err
+		}
+		*lastClaim = time.Now()
+	}
+
+	streams, err := s.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    s.group,
+		Consumer: s.consumer,
+		Streams:  []string{s.stream, ">"},
+		Count:    s.batchSize,
+		Block:    s.block,
+	}).Result()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil
+		}
+		return err
+	}
+
+	for _, stream := range streams {
+		for _, msg := range stream.Messages {
+			s.handle(ctx, msg)
+		}
+	}
+	return nil
+}
+
+func (s *StreamSubscriber) ensureGroup(ctx context.Context) error {
+	err := s.client.XGroupCreateMkStream(ctx, s.stream, s.group, "0").Err()
+	if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return err
+	}
+	return nil
+}
+
+// claim takes over entries that have been pending for longer than
+// claimMinIdle, regardless of which consumer they were delivered to. Entries
+// this consumer is still handling are left alone.
+func (s *StreamSubscriber) claim(ctx context.Context) error {
+	start := "0-0"
+	for {
+		msgs, next, err := s.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+			Stream:   s.stream,
+			Group:    s.group,
+			MinIdle:  s.claimMinIdle,
+			Start:    start,
+			Count:    s.batchSize,
+			Consumer: s.consumer,
+		}).Result()
+		if err != nil {
+			return err
+		}
+		for _, msg := range msgs {
+			if s.isInFlight(msg.ID) {
+				continue
+			}
+			s.logger.Info("claimed pending entry", "entry_id", msg.ID)
+			s.handle(ctx, msg)
+		}
+		if next == "0-0" || next == "" {
+			return nil
+		}
+		start = next
+	}
+}
+
+// handle decodes msg and submits it to the processor. Submitting gives up
+// when ctx is cancelled, leaving the entry pending for another consumer.
+func (s *StreamSubscriber) handle(ctx context.Context, msg redis.XMessage) {
+	data, ok := msg.Values[StreamField].(string)
+	if !ok {
+		s.logger.Error("invalid stream entry, missing data field", "entry_id", msg.ID)
+		s.ack(msg.ID)
+		return
+	}
+
+	var event events.Message
+	if err := s.decoder.decode([]byte(data), &event); err != nil {
+		s.logger.Error("invalid message", "entry_id", msg.ID, "error", err)
+		s.ack(msg.ID)
+		return
+	}
+	event.Channel = s.stream
+	span := startReceive(context.Background(), &event)
+	span.SetAttributes(attribute.String("messaging.redis.entry_id", msg.ID))
+
+	reply := s.acker.done(event)
+	s.setInFlight(msg.ID, true)
+	err := s.processor.SubmitContextWithAck(ctx, event, func(err error) {
+		s.setInFlight(msg.ID, false)
+		if reply != nil {
+			reply(err)
+		}
+		// a dead-lettered or spilled event has been dealt with and must not be
+		// claimed again
+		if err != nil && !errors.Is(err, processor.ErrDeadLettered) && !errors.Is(err, processor.ErrSpilled) {
+			// leave the entry pending so it can be claimed and retried
+			s.logger.Warn("event not acknowledged", "entry_id", msg.ID, "event_id", event.ID, "error", err)
+			return
+		}
+		s.ack(msg.ID)
+	})
+	if err != nil {
+		s.setInFlight(msg.ID, false)
+		s.logger.Warn("failed to submit event to processor", "entry_id", msg.ID, "event_id", event.ID, "error", err)
+		if reply != nil {
+			reply(err)
+		}
+	}
+	tracing.EndEvent(span, event, err)
+}
+
+// setInFlight records whether the entry id has been submitted to the
+// processor and not yet handled.
+func (s *StreamSubscriber) setInFlight(id string, inFlight bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if inFlight {
+		s.inFlight[id] = struct{}{}
+	} else {
+		delete(s.inFlight, id)
+	}
+}
+
+func (s *StreamSubscriber) isInFlight(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.inFlight[id]
+	return ok
+}
+
+func (s *StreamSubscriber) ack(id string) {
+	// acks may happen while the processor drains after ctx was cancelled
+	if err := s.client.XAck(context.Background(), s.stream, s.group, id).Err(); err != nil {
+		s.logger.Error("failed to ack stream entry", "entry_id", id, "error", err)
+	}
+}
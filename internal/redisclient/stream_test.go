@@ -0,0 +1,327 @@
+package redisclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/dedup"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/dispatcher"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/events"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/processor"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+type countingHandler struct {
+	calls atomic.Int32
+	fail  atomic.Bool
+}
+
+func (h *countingHandler) Handle(ctx context.Context, event events.Message) error {
+	h.calls.Add(1)
+	if h.fail.Load() {
+		return errors.New("simulated failure")
+	}
+	return nil
+}
+
+func addEvent(t *testing.T, client *redis.Client, stream string, event events.Message) string {
+	t.Helper()
+	data, err := json.Marshal(event)
+	if err != nil {
+		t.Fatalf("failed to marshal event: %v", err)
+	}
+	id, err := AddToStream(context.Background(), client, stream, data, 0)
+	if err != nil {
+		t.Fatalf("failed to add to stream: %v", err)
+	}
+	return id
+}
+
+func waitFor(t *testing.T, timeout time.Duration, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("condition not met before timeout")
+}
+
+func pendingCount(t *testing.T, client *redis.Client, stream, group string) int64 {
+	t.Helper()
+	res, err := client.XPending(context.Background(), stream, group).Result()
+	if err != nil {
+		t.Fatalf("XPENDING failed: %v", err)
+	}
+	return res.Count
+}
+
+func TestStreamSubscriberProcessesAndAcks(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client, err := New(mr.Addr(), 0)
+	if err != nil {
+		t.Fatalf("Failed to create Redis client: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	d := dispatcher.New(logger)
+	h := &countingHandler{}
+	d.Register("test", h)
+	p := processor.New(d, logger, 1, 10)
+	defer p.Stop()
+
+	addEvent(t, client, "events", events.Message{ID: "1", Type: "test"})
+
+	sub := NewStreamSubscriber(client, "events", "group", "consumer-1", p, logger, WithBlock(50*time.Millisecond))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- sub.Start(ctx) }()
+
+	waitFor(t, time.Second, func() bool { return h.calls.Load() == 1 })
+	waitFor(t, time.Second, func() bool { return pendingCount(t, client, "events", "group") == 0 })
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("expected clean shutdown, got %v", err)
+	}
+}
+
+func TestStreamSubscriberLeavesFailedEntriesPending(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client, err := New(mr.Addr(), 0)
+	if err != nil {
+		t.Fatalf("Failed to create Redis client: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	d := dispatcher.New(logger)
+	h := &countingHandler{}
+	h.fail.Store(true)
+	d.Register("test", h)
+	p := processor.New(d, logger, 1, 10)
+	defer p.Stop()
+
+	addEvent(t, client, "events", events.Message{ID: "1", Type: "test"})
+
+	sub := NewStreamSubscriber(client, "events", "group", "consumer-1", p, logger, WithBlock(50*time.Millisecond))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = sub.Start(ctx) }()
+
+	// one attempt plus three retries
+	waitFor(t, 2*time.Second, func() bool { return h.calls.Load() == 4 })
+	time.Sleep(50 * time.Millisecond)
+
+	if n := pendingCount(t, client, "events", "group"); n != 1 {
+		t.Fatalf("expected failed entry to stay pending, got %d pending", n)
+	}
+}
+
+func TestStreamSubscriberClaimsIdleEntries(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client, err := New(mr.Addr(), 0)
+	if err != nil {
+		t.Fatalf("Failed to create Redis client: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// a consumer that reads an entry and dies before acknowledging it
+	if err := client.XGroupCreateMkStream(ctx, "events", "group", "0").Err(); err != nil {
+		t.Fatalf("failed to create group: %v", err)
+	}
+	addEvent(t, client, "events", events.Message{ID: "1", Type: "test"})
+	if err := client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    "group",
+		Consumer: "crashed",
+		Streams:  []string{"events", ">"},
+	}).Err(); err != nil {
+		t.Fatalf("failed to read group: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	d := dispatcher.New(logger)
+	h := &countingHandler{}
+	d.Register("test", h)
+	p := processor.New(d, logger, 1, 10)
+	defer p.Stop()
+
+	sub := NewStreamSubscriber(client, "events", "group", "consumer-2", p, logger,
+		WithBlock(50*time.Millisecond),
+		WithClaimMinIdle(0),
+		WithClaimInterval(10*time.Millisecond),
+	)
+	go func() { _ = sub.Start(ctx) }()
+
+	waitFor(t, time.Second, func() bool { return h.calls.Load() >= 1 })
+	waitFor(t, time.Second, func() bool { return pendingCount(t, client, "events", "group") == 0 })
+}
+
+type blockingHandler struct {
+	calls   atomic.Int32
+	release chan struct{}
+}
+
+func (h *blockingHandler) Handle(ctx context.Context, event events.Message) error {
+	h.calls.Add(1)
+	<-h.release
+	return nil
+}
+
+func TestStreamSubscriberSkipsEntriesInFlight(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client, err := New(mr.Addr(), 0)
+	if err != nil {
+		t.Fatalf("Failed to create Redis client: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	d := dispatcher.New(logger)
+	h := &blockingHandler{release: make(chan struct{})}
+	d.Register("test", h)
+	p := processor.New(d, logger, 1, 10)
+	defer p.Stop()
+
+	addEvent(t, client, "events", events.Message{ID: "1", Type: "test"})
+
+	sub := NewStreamSubscriber(client, "events", "group", "consumer-1", p, logger,
+		WithBlock(10*time.Millisecond),
+		WithClaimMinIdle(0),
+		WithClaimInterval(10*time.Millisecond),
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = sub.Start(ctx) }()
+
+	// the entry is claimable the whole time it is being handled
+	waitFor(t, time.Second, func() bool { return h.calls.Load() == 1 })
+	time.Sleep(100 * time.Millisecond)
+	close(h.release)
+
+	waitFor(t, time.Second, func() bool { return pendingCount(t, client, "events", "group") == 0 })
+	time.Sleep(50 * time.Millisecond)
+	if n := h.calls.Load(); n != 1 {
+		t.Fatalf("expected the entry in flight to be handled once, got %d calls", n)
+	}
+}
+
+func TestStreamSubscriberStopsWhileBlockedOnFullQueue(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client, err := New(mr.Addr(), 0)
+	if err != nil {
+		t.Fatalf("Failed to create Redis client: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	d := dispatcher.New(logger)
+	h := &blockingHandler{release: make(chan struct{})}
+	d.Register("test", h)
+	p := processor.New(d, logger, 1, 1, processor.WithOverflowPolicy(processor.Block))
+	defer p.Stop()
+	defer close(h.release)
+
+	// one event running, one queued and one waiting for room
+	for _, id := range []string{"1", "2", "3"} {
+		addEvent(t, client, "events", events.Message{ID: id, Type: "test"})
+	}
+
+	sub := NewStreamSubscriber(client, "events", "group", "consumer-1", p, logger, WithBlock(10*time.Millisecond))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- sub.Start(ctx) }()
+
+	waitFor(t, time.Second, func() bool { return h.calls.Load() == 1 })
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected Start to return while the queue is full")
+	}
+}
+
+func TestStreamSubscriberRedeliversEventsClaimedByCrashedConsumer(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client, err := New(mr.Addr(), 0)
+	if err != nil {
+		t.Fatalf("Failed to create Redis client: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// a consumer that reads an entry, claims its ID and dies while handling it
+	store := dedup.NewRedis(client, "dedup:", time.Minute)
+	if err := client.XGroupCreateMkStream(ctx, "events", "group", "0").Err(); err != nil {
+		t.Fatalf("failed to create group: %v", err)
+	}
+	addEvent(t, client, "events", events.Message{ID: "1", Type: "test"})
+	if err := client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    "group",
+		Consumer: "crashed",
+		Streams:  []string{"events", ">"},
+	}).Err(); err != nil {
+		t.Fatalf("failed to read group: %v", err)
+	}
+	if _, _, err := store.Claim(ctx, "1", time.Second); err != nil {
+		t.Fatalf("failed to claim: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	d := dispatcher.New(logger)
+	h := &countingHandler{}
+	d.Register("test", h)
+	p := processor.New(d, logger, 1, 10, processor.WithDeduplication(store))
+	defer p.Stop()
+
+	sub := NewStreamSubscriber(client, "events", "group", "consumer-2", p, logger,
+		WithBlock(50*time.Millisecond),
+		WithClaimMinIdle(0),
+		WithClaimInterval(10*time.Millisecond),
+	)
+	go func() { _ = sub.Start(ctx) }()
+
+	// while the claim is held the entry is left pending, not acknowledged
+	time.Sleep(100 * time.Millisecond)
+	if h.calls.Load() != 0 || pendingCount(t, client, "events", "group") != 1 {
+		t.Fatalf("expected the entry to stay pending unhandled, got %d calls", h.calls.Load())
+	}
+
+	mr.FastForward(time.Second)
+	waitFor(t, time.Second, func() bool { return h.calls.Load() == 1 })
+	waitFor(t, time.Second, func() bool { return pendingCount(t, client, "events", "group") == 0 })
+}
+
+func TestStreamSubscriberAcksInvalidEntries(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client, err := New(mr.Addr(), 0)
+	if err != nil {
+		t.Fatalf("Failed to create Redis client: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	p := processor.New(dispatcher.New(logger), logger, 1, 10)
+	defer p.Stop()
+
+	if _, err := AddToStream(context.Background(), client, "events", []byte("not json"), 0); err != nil {
+		t.Fatalf("failed to add to stream: %v", err)
+	}
+
+	sub := NewStreamSubscriber(client, "events", "group", "consumer-1", p, logger, WithBlock(50*time.Millisecond))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = sub.Start(ctx) }()
+
+	// the entry is delivered to consumer-1 and acknowledged straight away
+	waitFor(t, time.Second, func() bool {
+		consumers, err := client.XInfoConsumers(context.Background(), "events", "group").Result()
+		return err == nil && len(consumers) == 1 && consumers[0].Pending == 0
+	})
+}
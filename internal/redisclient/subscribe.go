@@ -2,60 +2,325 @@ package redisclient
 
 import (
 	"context"
-	"encoding/json"
-	"errors"
 	"log/slog"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/backoff"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/codec"
 	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/events"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/keyring"
 	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/processor"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/tracing"
 
 	"github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Subscriber struct {
 	client    *redis.Client
-	channel   string
 	processor *processor.Processor
 	logger    *slog.Logger
+	backoff   backoff.Exponential
+
+	mu       sync.Mutex
+	channels map[string]struct{}
+	patterns map[string]struct{}
+	pubsub   *redis.PubSub
+	decoder  decoder
+	acker    *acker
+
+	connected   atomic.Bool
+	disconnects atomic.Int64
+	lastOutage  atomic.Int64
+	totalOutage atomic.Int64
+}
+
+// SubscriberStats describes the connection history of a Subscriber.
+type SubscriberStats struct {
+	Connected   bool
+	Disconnects int64
+	LastOutage  time.Duration
+	TotalOutage time.Duration
+}
+
+type SubscriberOption func(*Subscriber)
+
+// WithReconnectBackoff sets the delays used between resubscribe attempts.
+func WithReconnectBackoff(b backoff.Exponential) SubscriberOption {
+	return func(s *Subscriber) { s.backoff = b }
+}
+
+// WithCompressionObserver reports the size of every compressed message before
+// and after decompression.
+func WithCompressionObserver(observe codec.CompressionObserver) SubscriberOption {
+	return func(s *Subscriber) { s.decoder.observe = observe }
+}
+
+// WithVerifier checks message signatures with v before events are submitted
+// to the processor. Rejected messages are logged and counted by v.
+func WithVerifier(v *Verifier) SubscriberOption {
+	return func(s *Subscriber) { s.decoder.verifier = v }
+}
+
+// WithDecryption decrypts encrypted messages with the keys in keys. Without
+// it, encrypted messages are rejected.
+func WithDecryption(keys *keyring.Keyring) SubscriberOption {
+	return func(s *Subscriber) { s.decoder.keys = keys }
+}
+
+// WithAcks acknowledges events that carry a reply channel, such as those sent
+// with PublishAndCollect, as node once they have been handled.
+func WithAcks(node string) SubscriberOption {
+	return func(s *Subscriber) { s.acker = &acker{client: s.client, node: node, logger: s.logger} }
+}
+
+// WithChannels subscribes to additional channels.
+func WithChannels(channels ...string) SubscriberOption {
+	return func(s *Subscriber) { addAll(s.channels, channels) }
+}
+
+// WithPatterns subscribes to glob patterns such as "orders.*" with PSUBSCRIBE.
+func WithPatterns(patterns ...string) SubscriberOption {
+	return func(s *Subscriber) { addAll(s.patterns, patterns) }
 }
 
-func NewSubscriber(client *redis.Client, channel string, processor *processor.Processor, logger *slog.Logger) *Subscriber {
-	return &Subscriber{
+// NewSubscriber creates a subscriber for channel and any channels or patterns
+// given as options. channel may be empty when only options are used.
+func NewSubscriber(client *redis.Client, channel string, processor *processor.Processor, logger *slog.Logger, opts ...SubscriberOption) *Subscriber {
+	s := &Subscriber{
 		client:    client,
-		channel:   channel,
 		processor: processor,
 		logger:    logger,
+		backoff:   backoff.Default(),
+		channels:  make(map[string]struct{}),
+		patterns:  make(map[string]struct{}),
+	}
+	if channel != "" {
+		s.channels[channel] = struct{}{}
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Channels returns the channels currently subscribed to, sorted.
+func (s *Subscriber) Channels() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return sortedKeys(s.channels)
+}
+
+// Patterns returns the patterns currently subscribed to, sorted.
+func (s *Subscriber) Patterns() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return sortedKeys(s.patterns)
+}
+
+// Subscribe adds channels at runtime. They are kept across reconnects.
+func (s *Subscriber) Subscribe(ctx context.Context, channels ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	addAll(s.channels, channels)
+	if s.pubsub == nil {
+		return nil
+	}
+	return s.pubsub.Subscribe(ctx, channels...)
+}
+
+// Unsubscribe removes channels at runtime.
+func (s *Subscriber) Unsubscribe(ctx context.Context, channels ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	removeAll(s.channels, channels)
+	if s.pubsub == nil {
+		return nil
+	}
+	return s.pubsub.Unsubscribe(ctx, channels...)
+}
+
+// PSubscribe adds patterns at runtime. They are kept across reconnects.
+func (s *Subscriber) PSubscribe(ctx context.Context, patterns ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	addAll(s.patterns, patterns)
+	if s.pubsub == nil {
+		return nil
+	}
+	return s.pubsub.PSubscribe(ctx, patterns...)
+}
+
+// PUnsubscribe removes patterns at runtime.
+func (s *Subscriber) PUnsubscribe(ctx context.Context, patterns ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	removeAll(s.patterns, patterns)
+	if s.pubsub == nil {
+		return nil
 	}
+	return s.pubsub.PUnsubscribe(ctx, patterns...)
 }
 
+// Start subscribes and feeds received events to the processor until ctx is
+// cancelled. Connection failures never end the loop: the subscription is
+// re-established with jittered exponential backoff.
 func (s *Subscriber) Start(ctx context.Context) error {
-	sub := s.client.Subscribe(ctx, s.channel)
+	var downSince time.Time
+	attempt := 0
+
+	for {
+		err := s.run(ctx, func() {
+			if !downSince.IsZero() {
+				outage := time.Since(downSince)
+				s.lastOutage.Store(int64(outage))
+				s.totalOutage.Add(int64(outage))
+				s.logger.Info("reconnected to redis", "channels", s.Channels(), "patterns", s.Patterns(), "outage", outage, "attempts", attempt)
+			}
+			downSince = time.Time{}
+			attempt = 0
+		})
+		s.connected.Store(false)
+
+		// if context was cancelled, exit cleanly
+		if ctx.Err() != nil {
+			return nil
+		}
+
+		if downSince.IsZero() {
+			downSince = time.Now()
+			s.disconnects.Add(1)
+			s.logger.Warn("disconnected from redis", "channels", s.Channels(), "patterns", s.Patterns(), "error", err, "total_disconnects", s.disconnects.Load())
+		}
+
+		attempt++
+		delay := s.backoff.Delay(attempt)
+		s.logger.Info("reconnecting to redis", "channels", s.Channels(), "patterns", s.Patterns(), "attempt", attempt, "delay", delay)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(delay):
+		}
+	}
+}
+
+func (s *Subscriber) Stats() SubscriberStats {
+	return SubscriberStats{
+		Connected:   s.connected.Load(),
+		Disconnects: s.disconnects.Load(),
+		LastOutage:  time.Duration(s.lastOutage.Load()),
+		TotalOutage: time.Duration(s.totalOutage.Load()),
+	}
+}
+
+// run holds a single subscription until it fails. onSubscribed is called once
+// Redis has confirmed the subscription.
+func (s *Subscriber) run(ctx context.Context, onSubscribed func()) error {
+	sub, err := s.subscribe(ctx)
+	if err != nil {
+		return err
+	}
+	// pubsub reads do not observe ctx, so closing is what unblocks them
+	closeOnCancel := context.AfterFunc(ctx, func() { _ = sub.Close() })
 	defer func() {
+		s.mu.Lock()
+		s.pubsub = nil
+		s.mu.Unlock()
+		if !closeOnCancel() {
+			return
+		}
 		if err := sub.Close(); err != nil {
 			s.logger.Error("failed to close subscription", "error", err)
 		}
 	}()
 
-	s.logger.Info("subscribed to redis", "channel", s.channel)
+	// wait for the confirmation so a dead connection is noticed right away
+	if _, err := sub.Receive(ctx); err != nil {
+		return err
+	}
+	s.connected.Store(true)
+	onSubscribed()
+
+	s.logger.Info("subscribed to redis", "channels", s.Channels(), "patterns", s.Patterns())
 
 	for {
 		msg, err := sub.ReceiveMessage(ctx)
 		if err != nil {
-			// if context was cancelled, exit cleanly
-			if errors.Is(err, context.Canceled) {
-				return nil
-			}
 			return err
 		}
 
 		var event events.Message
-		if err := json.Unmarshal([]byte(msg.Payload), &event); err != nil {
-			s.logger.Error("invalid message", "error", err)
+		if err := s.decoder.decode([]byte(msg.Payload), &event); err != nil {
+			s.logger.Error("invalid message", "channel", msg.Channel, "error", err)
 			continue
 		}
-		if err := s.processor.Submit(event); err != nil {
+		event.Channel = msg.Channel
+		event.Pattern = msg.Pattern
+		span := startReceive(ctx, &event)
+		// with the Block overflow policy this holds up reading until there is
+		// room, pushing back on Redis instead of losing events
+		done := s.acker.done(event)
+		err = s.processor.SubmitContextWithAck(ctx, event, done)
+		if err != nil {
 			s.logger.Warn("failed to submit event to processor", "event_id", event.ID, "error", err)
+			if done != nil {
+				done(err)
+			}
 		}
+		tracing.EndEvent(span, event, err)
+	}
+}
+
+// subscribe opens a pubsub connection for the current channels and patterns
+// and publishes it for runtime changes.
+func (s *Subscriber) subscribe(ctx context.Context) (*redis.PubSub, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sub := s.client.Subscribe(ctx, sortedKeys(s.channels)...)
+	if len(s.patterns) > 0 {
+		if err := sub.PSubscribe(ctx, sortedKeys(s.patterns)...); err != nil {
+			_ = sub.Close()
+			return nil, err
+		}
+	}
+	s.pubsub = sub
+	return sub, nil
+}
+
+// startReceive starts the receive span of event as a child of the publisher's
+// span and hands its context on to the processor through the envelope.
+func startReceive(ctx context.Context, event *events.Message) trace.Span {
+	ctx, span := tracing.Tracer().Start(tracing.Extract(ctx, *event), "receive "+event.Channel,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		tracing.Attributes(*event),
+		trace.WithAttributes(attribute.String("messaging.destination.name", event.Channel)),
+	)
+	tracing.Inject(ctx, event)
+	return span
+}
+
+func addAll(set map[string]struct{}, values []string) {
+	for _, v := range values {
+		set[v] = struct{}{}
 	}
+}
 
+func removeAll(set map[string]struct{}, values []string) {
+	for _, v := range values {
+		delete(set, v)
+	}
+}
+
+func sortedKeys(set map[string]struct{}) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
 }
@@ -1,12 +1,23 @@
 package redisclient
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"log/slog"
 	"os"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/backoff"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/codec"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/dispatcher"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/events"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/keyring"
 	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/processor"
-	"github.com/alicebob/miniredis"
+	"github.com/alicebob/miniredis/v2"
 )
 
 func TestNewSubscriber(t *testing.T) {
@@ -24,8 +35,8 @@ func TestNewSubscriber(t *testing.T) {
 
 	mockSubscriber := NewSubscriber(client, "test-channel", &processor.Processor{}, slog.New(slog.NewTextHandler(os.Stdout, nil)))
 
-	if mockSubscriber.channel != "test-channel" {
-		t.Errorf("Expected channel to be 'test-channel', got '%s'", mockSubscriber.channel)
+	if channels := mockSubscriber.Channels(); len(channels) != 1 || channels[0] != "test-channel" {
+		t.Errorf("Expected channels to be [test-channel], got %v", channels)
 	}
 
 	if mockSubscriber.processor == nil {
@@ -41,3 +52,353 @@ func TestNewSubscriber(t *testing.T) {
 	}
 
 }
+
+func TestSubscriberReconnectsAfterDisconnect(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client, err := New(mr.Addr(), 0)
+	if err != nil {
+		t.Fatalf("Failed to create Redis client: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	d := dispatcher.New(logger)
+	h := &countingHandler{}
+	d.Register("test", h)
+	p := processor.New(d, logger, 1, 10)
+	defer p.Stop()
+
+	sub := NewSubscriber(client, "test-channel", p, logger, WithReconnectBackoff(backoff.Exponential{
+		Initial:    10 * time.Millisecond,
+		Max:        50 * time.Millisecond,
+		Multiplier: 2,
+	}))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- sub.Start(ctx) }()
+
+	publish := func() {
+		data, _ := json.Marshal(events.Message{ID: "1", Type: "test"})
+		mr.Publish("test-channel", string(data))
+	}
+
+	waitFor(t, time.Second, func() bool { return mr.PubSubNumSub("test-channel")["test-channel"] == 1 })
+	publish()
+	waitFor(t, time.Second, func() bool { return h.calls.Load() == 1 })
+
+	mr.Close()
+	waitFor(t, time.Second, func() bool { return sub.Stats().Disconnects == 1 })
+	if sub.Stats().Connected {
+		t.Fatal("expected subscriber to report disconnected")
+	}
+	time.Sleep(100 * time.Millisecond)
+	if err := mr.Restart(); err != nil {
+		t.Fatalf("failed to restart miniredis: %v", err)
+	}
+
+	waitFor(t, 2*time.Second, func() bool { return mr.PubSubNumSub("test-channel")["test-channel"] == 1 })
+	publish()
+	waitFor(t, time.Second, func() bool { return h.calls.Load() == 2 })
+
+	stats := sub.Stats()
+	if !stats.Connected {
+		t.Error("expected subscriber to report connected")
+	}
+	if stats.Disconnects != 1 {
+		t.Errorf("expected 1 disconnect, got %d", stats.Disconnects)
+	}
+	if stats.LastOutage < 100*time.Millisecond {
+		t.Errorf("expected outage of at least 100ms, got %s", stats.LastOutage)
+	}
+	if stats.TotalOutage != stats.LastOutage {
+		t.Errorf("expected total outage %s to equal last outage %s", stats.TotalOutage, stats.LastOutage)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("expected clean shutdown, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("subscriber did not stop after cancel")
+	}
+}
+
+type recordingHandler struct {
+	received chan events.Message
+}
+
+func (h *recordingHandler) Handle(ctx context.Context, event events.Message) error {
+	h.received <- event
+	return nil
+}
+
+func TestSubscriberChannelsAndPatterns(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client, err := New(mr.Addr(), 0)
+	if err != nil {
+		t.Fatalf("Failed to create Redis client: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	d := dispatcher.New(logger)
+	h := &recordingHandler{received: make(chan events.Message, 10)}
+	d.Register("test", h)
+	p := processor.New(d, logger, 1, 10)
+	defer p.Stop()
+
+	sub := NewSubscriber(client, "", p, logger,
+		WithChannels("alerts", "audit"),
+		WithPatterns("orders.*", "tenant:*:events"),
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = sub.Start(ctx) }()
+
+	waitFor(t, time.Second, func() bool {
+		return mr.PubSubNumSub("audit")["audit"] == 1 && mr.PubSubNumPat() == 2
+	})
+
+	data, _ := json.Marshal(events.Message{ID: "1", Type: "test"})
+	cases := []struct {
+		channel string
+		pattern string
+	}{
+		{"alerts", ""},
+		{"orders.created", "orders.*"},
+		{"tenant:42:events", "tenant:*:events"},
+	}
+	for _, c := range cases {
+		mr.Publish(c.channel, string(data))
+		select {
+		case event := <-h.received:
+			if event.Channel != c.channel || event.Pattern != c.pattern {
+				t.Errorf("expected channel %q and pattern %q, got %q and %q", c.channel, c.pattern, event.Channel, event.Pattern)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timed out waiting for message on %s", c.channel)
+		}
+	}
+}
+
+func TestSubscriberRuntimeChanges(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client, err := New(mr.Addr(), 0)
+	if err != nil {
+		t.Fatalf("Failed to create Redis client: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	p := processor.New(dispatcher.New(logger), logger, 1, 10)
+	defer p.Stop()
+
+	sub := NewSubscriber(client, "first", p, logger)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = sub.Start(ctx) }()
+
+	waitFor(t, time.Second, func() bool { return mr.PubSubNumSub("first")["first"] == 1 })
+
+	if err := sub.Subscribe(ctx, "second"); err != nil {
+		t.Fatalf("unexpected subscribe error: %v", err)
+	}
+	if err := sub.PSubscribe(ctx, "orders.*"); err != nil {
+		t.Fatalf("unexpected psubscribe error: %v", err)
+	}
+	if err := sub.Unsubscribe(ctx, "first"); err != nil {
+		t.Fatalf("unexpected unsubscribe error: %v", err)
+	}
+	waitFor(t, time.Second, func() bool {
+		n := mr.PubSubNumSub("first", "second")
+		return n["first"] == 0 && n["second"] == 1 && mr.PubSubNumPat() == 1
+	})
+
+	if err := sub.PUnsubscribe(ctx, "orders.*"); err != nil {
+		t.Fatalf("unexpected punsubscribe error: %v", err)
+	}
+	waitFor(t, time.Second, func() bool { return mr.PubSubNumPat() == 0 })
+
+	if channels := sub.Channels(); len(channels) != 1 || channels[0] != "second" {
+		t.Fatalf("expected channels [second], got %v", channels)
+	}
+	if patterns := sub.Patterns(); len(patterns) != 0 {
+		t.Fatalf("expected no patterns, got %v", patterns)
+	}
+}
+
+func TestSubscriberDecodesMixedCodecs(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client, err := New(mr.Addr(), 0)
+	if err != nil {
+		t.Fatalf("Failed to create Redis client: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	d := dispatcher.New(logger)
+	h := &recordingHandler{received: make(chan events.Message, 10)}
+	d.Register("test", h)
+	p := processor.New(d, logger, 1, 10)
+	defer p.Stop()
+
+	sub := NewSubscriber(client, "events", p, logger)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = sub.Start(ctx) }()
+	waitFor(t, time.Second, func() bool { return mr.PubSubNumSub("events")["events"] == 1 })
+
+	codecs := []codec.Codec{codec.JSON{}, codec.Msgpack{}, codec.CBOR{}, codec.Protobuf{}}
+	for _, c := range codecs {
+		pub := NewPublisher(client, "test-source", WithChannel("events"), WithCodec(c))
+		if _, _, err := pub.Publish(ctx, events.Message{ID: c.ContentType(), Type: "test", Payload: map[string]any{"n": 1}}); err != nil {
+			t.Fatalf("unexpected publish error: %v", err)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for range codecs {
+		select {
+		case event := <-h.received:
+			var payload struct {
+				N int `json:"n"`
+			}
+			if err := event.DecodePayload(&payload); err != nil || payload.N != 1 {
+				t.Fatalf("%s: unexpected payload %+v (%v)", event.ID, payload, err)
+			}
+			seen[event.ID] = true
+		case <-time.After(2 * time.Second):
+			t.Fatalf("expected an event from every codec, got %v", seen)
+		}
+	}
+}
+
+func TestSubscriberDecompresses(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client, err := New(mr.Addr(), 0)
+	if err != nil {
+		t.Fatalf("Failed to create Redis client: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	d := dispatcher.New(logger)
+	h := &recordingHandler{received: make(chan events.Message, 10)}
+	d.Register("test", h)
+	p := processor.New(d, logger, 1, 10)
+	defer p.Stop()
+
+	var mu sync.Mutex
+	var observed []string
+	observe := func(encoding string, compressed, original int) {
+		mu.Lock()
+		defer mu.Unlock()
+		observed = append(observed, encoding)
+	}
+	sub := NewSubscriber(client, "events", p, logger, WithCompressionObserver(observe))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = sub.Start(ctx) }()
+	waitFor(t, time.Second, func() bool { return mr.PubSubNumSub("events")["events"] == 1 })
+
+	text := strings.Repeat("lorem ipsum ", 500)
+	pub := NewPublisher(client, "test-source", WithChannel("events"), WithCompression(codec.Zstd, 1024))
+	for _, payload := range []string{"short", text} {
+		if _, _, err := pub.Publish(ctx, events.Message{Type: "test", Payload: payload}); err != nil {
+			t.Fatalf("unexpected publish error: %v", err)
+		}
+	}
+
+	for _, want := range []string{"short", text} {
+		select {
+		case event := <-h.received:
+			var payload string
+			if err := event.DecodePayload(&payload); err != nil || payload != want {
+				t.Fatalf("unexpected payload of %d bytes (%v)", len(payload), err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("expected event to be delivered")
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(observed) != 1 || observed[0] != codec.Zstd {
+		t.Fatalf("expected only the large event to be compressed, got %v", observed)
+	}
+}
+
+func TestSubscriberDecrypts(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client, err := New(mr.Addr(), 0)
+	if err != nil {
+		t.Fatalf("Failed to create Redis client: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	d := dispatcher.New(logger)
+	h := &recordingHandler{received: make(chan events.Message, 10)}
+	d.Register("test", h)
+	p := processor.New(d, logger, 1, 10)
+	defer p.Stop()
+
+	oldKey, newKey := []byte(strings.Repeat("o", 32)), []byte(strings.Repeat("n", 32))
+	subKeys := keyring.New()
+	subKeys.Add("old", oldKey)
+	subKeys.Add("new", newKey)
+	sub := NewSubscriber(client, "events", p, logger, WithDecryption(subKeys))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = sub.Start(ctx) }()
+	waitFor(t, time.Second, func() bool { return mr.PubSubNumSub("events")["events"] == 1 })
+
+	pubKeys := keyring.New()
+	pubKeys.Add("old", oldKey)
+	pubKeys.Add("new", newKey)
+	pub := NewPublisher(client, "test-source", WithChannel("events"), WithEncryption(pubKeys))
+	if _, _, err := pub.Publish(ctx, events.Message{ID: "1", Type: "test", Payload: "secret"}); err != nil {
+		t.Fatalf("unexpected publish error: %v", err)
+	}
+	if err := pubKeys.SetCurrent("new"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err := pub.Publish(ctx, events.Message{ID: "2", Type: "test", Payload: "secret"}); err != nil {
+		t.Fatalf("unexpected publish error: %v", err)
+	}
+	// plaintext events are still accepted next to encrypted ones
+	if _, _, err := NewPublisher(client, "test-source", WithChannel("events")).Publish(ctx, events.Message{ID: "3", Type: "test"}); err != nil {
+		t.Fatalf("unexpected publish error: %v", err)
+	}
+
+	for _, want := range []string{"1", "2", "3"} {
+		select {
+		case event := <-h.received:
+			if event.ID != want || event.Encrypted != (want != "3") {
+				t.Fatalf("unexpected event %s, encrypted %v", event.ID, event.Encrypted)
+			}
+			if want != "3" {
+				var payload string
+				if err := event.DecodePayload(&payload); err != nil || payload != "secret" {
+					t.Fatalf("unexpected payload %q (%v)", payload, err)
+				}
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("expected event %s to be delivered", want)
+		}
+	}
+}
+
+func TestDecoderRejectsEncryptedWithoutKeys(t *testing.T) {
+	keys := keyring.New()
+	keys.Add("k1", []byte(strings.Repeat("k", 32)))
+	pub := NewPublisher(nil, "test-source", WithEncryption(keys))
+	data, err := pub.encode(events.Message{ID: "1", Type: "test"})
+	if err != nil {
+		t.Fatalf("unexpected encode error: %v", err)
+	}
+
+	var event events.Message
+	if err := (decoder{}).decode(data, &event); !errors.Is(err, ErrNoDecryptionKeys) {
+		t.Fatalf("expected ErrNoDecryptionKeys, got %v", err)
+	}
+	if err := (decoder{keys: keyring.New()}).decode(data, &event); !errors.Is(err, codec.ErrDecrypt) {
+		t.Fatalf("expected ErrDecrypt for an unknown key, got %v", err)
+	}
+}
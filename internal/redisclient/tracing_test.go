@@ -0,0 +1,86 @@
+package redisclient
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/dispatcher"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/events"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/processor"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/tracing"
+	"github.com/alicebob/miniredis/v2"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestTraceContextFollowsEvent(t *testing.T) {
+	exporter := tracing.NewMemoryExporter()
+	provider := tracing.Setup("test", exporter)
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+
+	mr := miniredis.RunT(t)
+	client, err := New(mr.Addr(), 0)
+	if err != nil {
+		t.Fatalf("Failed to create Redis client: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	d := dispatcher.New(logger)
+	h := &recordingHandler{received: make(chan events.Message, 1)}
+	d.Register("test", h, dispatcher.WithName("recorder"))
+	p := processor.New(d, logger, 1, 10)
+
+	sub := NewSubscriber(client, "events", p, logger)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = sub.Start(ctx) }()
+	waitFor(t, time.Second, func() bool { return mr.PubSubNumSub("events")["events"] == 1 })
+
+	reqCtx, request := tracing.Tracer().Start(context.Background(), "request")
+	pub := NewPublisher(client, "test-source", WithChannel("events"))
+	if _, _, err := pub.Publish(reqCtx, events.Message{Type: "test"}); err != nil {
+		t.Fatalf("unexpected publish error: %v", err)
+	}
+	request.End()
+
+	select {
+	case <-h.received:
+	case <-time.After(2 * time.Second):
+		t.Fatal("event was not handled")
+	}
+	p.Stop()
+	_ = provider.ForceFlush(context.Background())
+
+	spans := make(map[string]trace.SpanContext)
+	parents := make(map[string]trace.SpanID)
+	for _, s := range exporter.GetSpans() {
+		spans[s.Name] = s.SpanContext
+		parents[s.Name] = s.Parent.SpanID()
+	}
+	for _, name := range []string{"publish events", "receive events", "queue", "dispatch", "attempt", "handle recorder"} {
+		sc, ok := spans[name]
+		if !ok {
+			t.Fatalf("expected a %q span, got %v", name, spans)
+		}
+		if sc.TraceID() != request.SpanContext().TraceID() {
+			t.Errorf("expected %q to be part of the request trace", name)
+		}
+	}
+	for child, parent := range map[string]string{
+		"publish events":  "request",
+		"receive events":  "publish events",
+		"queue":           "receive events",
+		"dispatch":        "receive events",
+		"attempt":         "dispatch",
+		"handle recorder": "attempt",
+	} {
+		if parents[child] != spans[parent].SpanID() {
+			t.Errorf("expected %q to be a child of %q", child, parent)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package redisclient
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/codec"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/keyring"
+)
+
+var (
+	// ErrUnsigned rejects an unsigned message of an event type that requires
+	// a signature.
+	ErrUnsigned   = errors.New("message is not signed")
+	ErrUnknownKey = errors.New("message signed with unknown key")
+)
+
+// SignaturePolicy decides whether an event type must be signed.
+type SignaturePolicy int
+
+const (
+	// SignatureOptional accepts unsigned messages. Signed messages are still
+	// verified.
+	SignatureOptional SignaturePolicy = iota
+	SignatureRequired
+)
+
+// Verifier checks message signatures before events reach the processor.
+// Messages with a bad signature or an unknown key are always rejected;
+// unsigned messages are rejected when their event type requires a signature.
+type Verifier struct {
+	keys   *keyring.Keyring
+	policy SignaturePolicy
+	byType map[string]SignaturePolicy
+
+	verified atomic.Int64
+	unsigned atomic.Int64
+	rejected [3]atomic.Int64 // by reason, see rejectReasons
+}
+
+// VerifierStats counts the messages a Verifier has seen.
+type VerifierStats struct {
+	Verified int64 // signed messages accepted
+	Unsigned int64 // unsigned messages accepted under SignatureOptional
+	// Rejected messages by reason: "unsigned", "unknown_key" or
+	// "bad_signature".
+	Rejected map[string]int64
+}
+
+var rejectReasons = [...]string{"unsigned", "unknown_key", "bad_signature"}
+
+type VerifierOption func(*Verifier)
+
+// WithEventSignaturePolicy overrides the policy for a single event type.
+func WithEventSignaturePolicy(eventType string, policy SignaturePolicy) VerifierOption {
+	return func(v *Verifier) { v.byType[eventType] = policy }
+}
+
+// NewVerifier creates a verifier accepting messages signed with any key in
+// keys. policy applies to event types without an override.
+func NewVerifier(keys *keyring.Keyring, policy SignaturePolicy, opts ...VerifierOption) *Verifier {
+	v := &Verifier{keys: keys, policy: policy, byType: make(map[string]SignaturePolicy)}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+// Verify checks the signature of f, which decoded to an event of eventType.
+func (v *Verifier) Verify(f codec.Frame, eventType string) error {
+	if f.Signed() {
+		return v.verifySigned(f)
+	}
+	return v.verifyUnsigned(eventType)
+}
+
+// verifySigned checks the signature of a signed frame. It needs nothing but
+// the raw frame, so it runs before the body is decrypted or decoded.
+func (v *Verifier) verifySigned(f codec.Frame) error {
+	key, err := v.keys.Key(f.KeyID())
+	if err != nil {
+		v.rejected[1].Add(1)
+		return fmt.Errorf("%w %q", ErrUnknownKey, f.KeyID())
+	}
+	if err := f.Verify(key); err != nil {
+		v.rejected[2].Add(1)
+		return err
+	}
+	v.verified.Add(1)
+	return nil
+}
+
+// verifyUnsigned applies the policy of eventType to an unsigned frame.
+func (v *Verifier) verifyUnsigned(eventType string) error {
+	policy, ok := v.byType[eventType]
+	if !ok {
+		policy = v.policy
+	}
+	if policy == SignatureRequired {
+		v.rejected[0].Add(1)
+		return ErrUnsigned
+	}
+	v.unsigned.Add(1)
+	return nil
+}
+
+func (v *Verifier) Stats() VerifierStats {
+	stats := VerifierStats{
+		Verified: v.verified.Load(),
+		Unsigned: v.unsigned.Load(),
+		Rejected: make(map[string]int64, len(rejectReasons)),
+	}
+	for i, reason := range rejectReasons {
+		stats.Rejected[reason] = v.rejected[i].Load()
+	}
+	return stats
+}
@@ -0,0 +1,138 @@
+package redisclient
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/codec"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/dispatcher"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/events"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/keyring"
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/processor"
+	"github.com/alicebob/miniredis/v2"
+)
+
+func TestVerifierPolicies(t *testing.T) {
+	keys := keyring.New()
+	keys.Add("k1", []byte("secret"))
+	v := NewVerifier(keys, SignatureOptional, WithEventSignaturePolicy("payment.captured", SignatureRequired))
+
+	unsigned := codec.Frame{ContentType: "application/json", Body: []byte("{}")}
+	if err := v.Verify(unsigned, "demo.message"); err != nil {
+		t.Fatalf("expected unsigned message to be accepted, got %v", err)
+	}
+	if err := v.Verify(unsigned, "payment.captured"); !errors.Is(err, ErrUnsigned) {
+		t.Fatalf("expected ErrUnsigned, got %v", err)
+	}
+	if err := v.Verify(unsigned.Sign("k1", []byte("secret")), "payment.captured"); err != nil {
+		t.Fatalf("expected signed message to be accepted, got %v", err)
+	}
+	if err := v.Verify(unsigned.Sign("k2", []byte("secret")), "demo.message"); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("expected ErrUnknownKey, got %v", err)
+	}
+	if err := v.Verify(unsigned.Sign("k1", []byte("forged")), "demo.message"); !errors.Is(err, codec.ErrBadSignature) {
+		t.Fatalf("expected ErrBadSignature, got %v", err)
+	}
+
+	stats := v.Stats()
+	if stats.Verified != 1 || stats.Unsigned != 1 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+	for _, reason := range []string{"unsigned", "unknown_key", "bad_signature"} {
+		if stats.Rejected[reason] != 1 {
+			t.Fatalf("expected one %s rejection, got %+v", reason, stats.Rejected)
+		}
+	}
+}
+
+func TestSubscriberRejectsForgedMessages(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client, err := New(mr.Addr(), 0)
+	if err != nil {
+		t.Fatalf("Failed to create Redis client: %v", err)
+	}
+
+	logger := slog.New(slog.NewTextHandler(os.Stdout, nil))
+	d := dispatcher.New(logger)
+	h := &recordingHandler{received: make(chan events.Message, 10)}
+	d.Register("test", h)
+	p := processor.New(d, logger, 1, 10)
+	defer p.Stop()
+
+	// the subscriber still accepts the retired key during rotation
+	subKeys := keyring.New()
+	subKeys.Add("old", []byte("old-secret"))
+	subKeys.Add("new", []byte("new-secret"))
+	v := NewVerifier(subKeys, SignatureRequired)
+	sub := NewSubscriber(client, "events", p, logger, WithVerifier(v))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = sub.Start(ctx) }()
+	waitFor(t, time.Second, func() bool { return mr.PubSubNumSub("events")["events"] == 1 })
+
+	forgedKeys := keyring.New()
+	forgedKeys.Add("new", []byte("guessed"))
+	pubKeys := keyring.New()
+	pubKeys.Add("old", []byte("old-secret"))
+	pubKeys.Add("new", []byte("new-secret"))
+	publishers := []*Publisher{
+		NewPublisher(client, "test-source", WithChannel("events")),
+		NewPublisher(client, "test-source", WithChannel("events"), WithSigning(forgedKeys)),
+		NewPublisher(client, "test-source", WithChannel("events"), WithSigning(pubKeys)),
+	}
+	for i, pub := range publishers {
+		if _, _, err := pub.Publish(ctx, events.Message{ID: string(rune('a' + i)), Type: "test"}); err != nil {
+			t.Fatalf("unexpected publish error: %v", err)
+		}
+	}
+	if err := pubKeys.SetCurrent("new"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err := publishers[2].Publish(ctx, events.Message{ID: "d", Type: "test"}); err != nil {
+		t.Fatalf("unexpected publish error: %v", err)
+	}
+
+	for _, want := range []string{"c", "d"} {
+		select {
+		case event := <-h.received:
+			if event.ID != want {
+				t.Fatalf("expected event %s, got %s", want, event.ID)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("expected event %s to be delivered", want)
+		}
+	}
+	stats := v.Stats()
+	if stats.Verified != 2 || stats.Rejected["unsigned"] != 1 || stats.Rejected["bad_signature"] != 1 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
+
+func TestDecoderVerifiesBeforeDecrypting(t *testing.T) {
+	encKeys := keyring.New()
+	encKeys.Add("e1", []byte(strings.Repeat("e", 32)))
+	forged := keyring.New()
+	forged.Add("k1", []byte("forged"))
+	pub := NewPublisher(nil, "test-source", WithEncryption(encKeys), WithSigning(forged))
+	data, err := pub.encode(events.Message{ID: "1", Type: "test"})
+	if err != nil {
+		t.Fatalf("unexpected encode error: %v", err)
+	}
+
+	// without decryption keys the forgery is still caught by its signature
+	keys := keyring.New()
+	keys.Add("k1", []byte("secret"))
+	v := NewVerifier(keys, SignatureRequired)
+	var event events.Message
+	if err := (decoder{verifier: v}).decode(data, &event); !errors.Is(err, codec.ErrBadSignature) {
+		t.Fatalf("expected ErrBadSignature, got %v", err)
+	}
+	if event.ID != "" {
+		t.Fatalf("expected the forged message not to be decoded, got %+v", event)
+	}
+}
@@ -0,0 +1,110 @@
+// Package tracing propagates OpenTelemetry trace context through events using
+// the W3C traceparent and tracestate headers of the envelope.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/events"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const name = "github.com/aaryan-purohit/message-broadcast-redis-pub-sub"
+
+var propagator = propagation.TraceContext{}
+
+// Tracer returns the tracer used for all broadcast spans. Until Setup is
+// called it comes from the global provider, which is a no-op by default.
+func Tracer() trace.Tracer {
+	return otel.Tracer(name)
+}
+
+// Setup installs a global tracer provider that batches spans to exporter.
+// Shut the provider down on exit to flush the remaining spans.
+func Setup(service string, exporter sdktrace.SpanExporter) *sdktrace.TracerProvider {
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", service))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider
+}
+
+// NewStdoutExporter writes finished spans to w as JSON.
+func NewStdoutExporter(w io.Writer) (sdktrace.SpanExporter, error) {
+	return stdouttrace.New(stdouttrace.WithWriter(w))
+}
+
+// NewMemoryExporter keeps finished spans in memory, so tests can assert on
+// them offline.
+func NewMemoryExporter() *tracetest.InMemoryExporter {
+	return tracetest.NewInMemoryExporter()
+}
+
+// Inject stores the span context of ctx in event. Without a valid span the
+// headers are cleared.
+func Inject(ctx context.Context, event *events.Message) {
+	event.SetHeader(events.HeaderTraceParent, "")
+	event.SetHeader(events.HeaderTraceState, "")
+	propagator.Inject(ctx, carrier{event})
+}
+
+// Extract returns ctx with the remote span context carried by event, if any.
+func Extract(ctx context.Context, event events.Message) context.Context {
+	return propagator.Extract(ctx, carrier{&event})
+}
+
+// Attributes describes event on a span.
+func Attributes(event events.Message) trace.SpanStartOption {
+	return trace.WithAttributes(
+		attribute.String("messaging.system", "redis"),
+		attribute.String("messaging.message.id", event.ID),
+		attribute.String("event.type", event.Type),
+	)
+}
+
+// End records err, if any, on span and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// EndEvent is End for a span about event. The error of an encrypted event
+// is redacted, as it may quote the payload and spans leave the process.
+func EndEvent(span trace.Span, event events.Message, err error) {
+	if err != nil && event.Encrypted {
+		err = errors.New(events.Redacted)
+	}
+	End(span, err)
+}
+
+// carrier gives the propagator access to the trace headers of an event.
+type carrier struct {
+	event *events.Message
+}
+
+func (c carrier) Get(key string) string {
+	return c.event.Header(key)
+}
+
+func (c carrier) Set(key, value string) {
+	c.event.SetHeader(key, value)
+}
+
+func (c carrier) Keys() []string {
+	return []string{events.HeaderTraceParent, events.HeaderTraceState}
+}
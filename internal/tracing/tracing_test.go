@@ -0,0 +1,106 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aaryan-purohit/message-broadcast-redis-pub-sub/internal/events"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func useExporter(t *testing.T) (*bytes.Buffer, func()) {
+	t.Helper()
+	var buf bytes.Buffer
+	exporter, err := NewStdoutExporter(&buf)
+	if err != nil {
+		t.Fatalf("failed to create exporter: %v", err)
+	}
+	provider := Setup("test", exporter)
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+	return &buf, func() { _ = provider.ForceFlush(context.Background()) }
+}
+
+func TestInjectExtract(t *testing.T) {
+	useExporter(t)
+
+	ctx, span := Tracer().Start(context.Background(), "publish")
+	defer span.End()
+
+	var event events.Message
+	Inject(ctx, &event)
+	if event.Header(events.HeaderTraceParent) == "" {
+		t.Fatal("expected traceparent to be set")
+	}
+
+	remote := trace.SpanContextFromContext(Extract(context.Background(), event))
+	if remote.TraceID() != span.SpanContext().TraceID() || remote.SpanID() != span.SpanContext().SpanID() {
+		t.Fatalf("expected extracted span context %v, got %v", span.SpanContext(), remote)
+	}
+	if !remote.IsRemote() {
+		t.Fatal("expected extracted span context to be remote")
+	}
+}
+
+func TestInjectWithoutSpanClearsHeaders(t *testing.T) {
+	event := events.Message{Headers: map[string]string{
+		events.HeaderTraceParent:   "00-stale-stale-01",
+		events.HeaderTraceState:    "a=b",
+		events.HeaderCorrelationID: "c-1",
+	}}
+	Inject(context.Background(), &event)
+	if event.Header(events.HeaderTraceParent) != "" || event.Header(events.HeaderTraceState) != "" {
+		t.Fatalf("expected trace headers to be cleared, got %v", event.Headers)
+	}
+	if event.CorrelationID() != "c-1" {
+		t.Fatalf("expected other headers to be kept, got %v", event.Headers)
+	}
+	if trace.SpanContextFromContext(Extract(context.Background(), event)).IsValid() {
+		t.Fatal("expected no span context")
+	}
+}
+
+func TestEndRecordsError(t *testing.T) {
+	exporter := NewMemoryExporter()
+	provider := Setup("test", exporter)
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+
+	_, span := Tracer().Start(context.Background(), "handle")
+	End(span, errors.New("boom"))
+	_ = provider.ForceFlush(context.Background())
+
+	spans := exporter.GetSpans()
+	if len(spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(spans))
+	}
+	if spans[0].Status.Code != codes.Error || spans[0].Status.Description != "boom" {
+		t.Fatalf("expected error status, got %+v", spans[0].Status)
+	}
+}
+
+func TestEndEventRedactsEncryptedErrors(t *testing.T) {
+	buf, flush := useExporter(t)
+	_, span := Tracer().Start(context.Background(), "handle")
+	EndEvent(span, events.Message{Encrypted: true}, errors.New("bad card 4242"))
+	flush()
+
+	if bytes.Contains(buf.Bytes(), []byte("4242")) || !bytes.Contains(buf.Bytes(), []byte(events.Redacted)) {
+		t.Fatalf("expected the error to be redacted, got %s", buf.String())
+	}
+}
+
+func TestStdoutExporter(t *testing.T) {
+	buf, flush := useExporter(t)
+	_, span := Tracer().Start(context.Background(), "receive")
+	span.End()
+	flush()
+
+	if !bytes.Contains(buf.Bytes(), []byte(`"Name":"receive"`)) {
+		t.Fatalf("expected span to be written, got %s", buf.String())
+	}
+}